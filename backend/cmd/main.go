@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/chmenegatti/myBlog/internal/app"
 	"github.com/chmenegatti/myBlog/internal/config"
@@ -19,6 +23,7 @@ func main() {
 	if err := logger.Initialize(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.Output); err != nil {
 		log.Fatal("Failed to initialize logger:", err)
 	}
+	defer logger.Close()
 
 	// Log application startup
 	logger.WithService("main").Info("Starting Blog API application", map[string]any{
@@ -33,20 +38,36 @@ func main() {
 		logger.WithService("main").Error("Failed to initialize application", map[string]any{
 			"error": err.Error(),
 		})
+		logger.Close()
 		log.Fatal("Failed to initialize application:", err)
 	}
 
-	// Start the server
+	// Start the server; SIGINT or SIGTERM starts a graceful shutdown
 	logger.WithService("main").Info("Server starting", map[string]any{
 		"port":       cfg.Server.Port,
 		"log_level":  cfg.Logging.Level,
 		"log_output": cfg.Logging.Output,
 	})
 
-	if err := application.Start(); err != nil {
-		logger.WithService("main").Error("Failed to start server", map[string]any{
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	runErr := application.Run(ctx)
+	// A second signal kills the process right away
+	stop()
+
+	if runErr != nil {
+		logger.WithService("main").Error("Server stopped with an error", map[string]any{
+			"error": runErr.Error(),
+		})
+	}
+	if err := application.Close(); err != nil {
+		logger.WithService("main").Error("Failed to shut down cleanly", map[string]any{
 			"error": err.Error(),
 		})
-		log.Fatal("Failed to start server:", err)
+	}
+	logger.WithService("main").Info("Server stopped")
+
+	if runErr != nil {
+		logger.Close()
+		log.Fatal("Server stopped with an error:", runErr)
 	}
 }
@@ -1,150 +1,128 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/database"
+	"github.com/chmenegatti/myBlog/migrations"
 	_ "github.com/lib/pq"
 )
 
+const usage = `Usage: migrate [-dir migrations] <command>
+
+Commands:
+  up             apply all pending migrations
+  down [steps]   roll back the last applied migrations (default 1)
+  status         list migrations and whether they are applied
+  create <name>  create empty up and down files for a new migration
+
+up, down and status use the migrations embedded in this binary; create
+writes to -dir. The database is configured like the API (DATABASE_URL or
+DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME and DB_SSL_MODE).
+`
+
 func main() {
-	// Get DATABASE_URL from environment
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Fatal("DATABASE_URL environment variable is required")
+	dir := flag.String("dir", "migrations", "directory new migrations are created in")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		paths, err := database.CreateMigration(*dir, args[1])
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		for _, path := range paths {
+			fmt.Println("📝 Created", path)
+		}
+		return
 	}
 
-	// Connect to database
-	db, err := sql.Open("postgres", databaseURL)
+	db, err := sql.Open("postgres", database.DSN(config.LoadDatabase()))
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	// Test connection
 	if err := db.Ping(); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
-	fmt.Println("🚀 Connected to database, running migrations...")
-
-	// Run categories migration
-	if err := seedCategories(db); err != nil {
-		log.Printf("Categories migration failed (might already exist): %v", err)
-	} else {
-		fmt.Println("✅ Categories seeded successfully")
-	}
-
-	// Run tags migration
-	if err := seedTags(db); err != nil {
-		log.Printf("Tags migration failed (might already exist): %v", err)
-	} else {
-		fmt.Println("✅ Tags seeded successfully")
-	}
-
-	// Check results
-	var categoryCount, tagCount int
-	db.QueryRow("SELECT COUNT(*) FROM categories").Scan(&categoryCount)
-	db.QueryRow("SELECT COUNT(*) FROM tags").Scan(&tagCount)
-
-	fmt.Printf("📊 Database contains %d categories and %d tags\n", categoryCount, tagCount)
-	fmt.Println("🎉 Migration completed!")
-}
-
-func seedCategories(db *sql.DB) error {
-	categories := []struct {
-		name, slug, description, color string
-	}{
-		{"Technology", "technology", "Articles about technology, programming, and software development", "#3B82F6"},
-		{"Web Development", "web-development", "Frontend and backend development tutorials and tips", "#10B981"},
-		{"Design", "design", "UI/UX design, visual design, and design thinking", "#8B5CF6"},
-		{"Programming", "programming", "Programming languages, algorithms, and coding practices", "#F59E0B"},
-		{"DevOps", "devops", "DevOps practices, CI/CD, and infrastructure", "#EF4444"},
-		{"Mobile", "mobile", "Mobile app development for iOS and Android", "#06B6D4"},
-		{"Database", "database", "Database design, optimization, and management", "#84CC16"},
-		{"Cloud Computing", "cloud-computing", "Cloud platforms, serverless, and distributed systems", "#A855F7"},
-		{"AI & Machine Learning", "ai-machine-learning", "Artificial Intelligence and Machine Learning topics", "#EC4899"},
-		{"Career", "career", "Career advice, interviews, and professional development", "#F97316"},
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
 	}
 
-	for _, cat := range categories {
-		_, err := db.Exec(`
-			INSERT INTO categories (id, name, slug, description, color, created_at, updated_at) 
-			VALUES (gen_random_uuid(), $1, $2, $3, $4, NOW(), NOW())
-			ON CONFLICT (slug) DO NOTHING`,
-			cat.name, cat.slug, cat.description, cat.color)
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("✅ Applied %04d_%s\n", migration.Version, migration.Name)
+		}
 		if err != nil {
-			return err
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("🎉 Database is up to date")
 		}
-	}
-	return nil
-}
 
-func seedTags(db *sql.DB) error {
-	tags := []struct {
-		name, slug string
-	}{
-		// Programming Languages
-		{"JavaScript", "javascript"},
-		{"TypeScript", "typescript"},
-		{"Python", "python"},
-		{"Go", "go"},
-		{"Java", "java"},
-		{"C#", "csharp"},
-		{"PHP", "php"},
-		{"Rust", "rust"},
-		// Frameworks
-		{"React", "react"},
-		{"Vue.js", "vuejs"},
-		{"Angular", "angular"},
-		{"Next.js", "nextjs"},
-		{"Node.js", "nodejs"},
-		{"Express", "express"},
-		{"Django", "django"},
-		{"Flask", "flask"},
-		{"Spring Boot", "spring-boot"},
-		{"Laravel", "laravel"},
-		// Databases
-		{"PostgreSQL", "postgresql"},
-		{"MySQL", "mysql"},
-		{"MongoDB", "mongodb"},
-		{"Redis", "redis"},
-		// Cloud & DevOps
-		{"AWS", "aws"},
-		{"Azure", "azure"},
-		{"Google Cloud", "google-cloud"},
-		{"Docker", "docker"},
-		{"Kubernetes", "kubernetes"},
-		{"CI/CD", "cicd"},
-		// Tools
-		{"Git", "git"},
-		{"VS Code", "vscode"},
-		{"Figma", "figma"},
-		{"API", "api"},
-		{"REST", "rest"},
-		{"GraphQL", "graphql"},
-		// Concepts
-		{"Microservices", "microservices"},
-		{"Clean Code", "clean-code"},
-		{"Testing", "testing"},
-		{"Performance", "performance"},
-		{"Security", "security"},
-		{"Tutorial", "tutorial"},
-		{"Best Practices", "best-practices"},
-		{"Tips", "tips"},
-	}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("↩️  Rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("Nothing to roll back")
+		}
 
-	for _, tag := range tags {
-		_, err := db.Exec(`
-			INSERT INTO tags (id, name, slug, created_at, updated_at) 
-			VALUES (gen_random_uuid(), $1, $2, NOW(), NOW())
-			ON CONFLICT (slug) DO NOTHING`,
-			tag.name, tag.slug)
+	case "status":
+		statuses, err := migrator.Status(ctx)
 		if err != nil {
-			return err
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Modified {
+				state += " (modified since applied)"
+			}
+			if status.Unknown {
+				state += " (not in this build)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
 		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
 	}
-	return nil
 }
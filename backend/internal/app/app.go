@@ -1,53 +1,152 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/chmenegatti/myBlog/internal/cache"
 	"github.com/chmenegatti/myBlog/internal/config"
 	"github.com/chmenegatti/myBlog/internal/database"
 	"github.com/chmenegatti/myBlog/internal/handlers"
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/mailer"
+	"github.com/chmenegatti/myBlog/internal/metrics"
 	"github.com/chmenegatti/myBlog/internal/middleware"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/ratelimit"
 	"github.com/chmenegatti/myBlog/internal/repositories"
 	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/chmenegatti/myBlog/internal/tracing"
 	"github.com/gin-gonic/gin"
 )
 
 type App struct {
-	config *config.Config
-	router *gin.Engine
-	db     *database.DB
+	config    *config.Config
+	router    *gin.Engine
+	server    *http.Server
+	db        *database.DB
+	mail      *mailer.Async
+	publisher *services.ScheduledPublisher
+	limiter   *ratelimit.Limiter
+	cache     *cache.Cache
+	tracing   func(context.Context) error // flushes buffered spans
 }
 
 func New(cfg *config.Config) (*App, error) {
+	// Set up tracing first so the database plugin picks up the tracer provider
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, cfg.Server.Env)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize database
 	db, err := database.New(cfg.Database)
 	if err != nil {
 		return nil, err
 	}
 
+	// Search queries must use the language the search column was built with
+	if err := db.CheckSearchLanguage(cfg.Search.Language); err != nil {
+		return nil, err
+	}
+
+	migrator, err := db.Migrator()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db.GetDB())
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db.GetDB())
+	passwordResetRepo := repositories.NewPasswordResetRepository(db.GetDB())
+	recoveryCodeRepo := repositories.NewRecoveryCodeRepository(db.GetDB())
+	apiTokenRepo := repositories.NewAPITokenRepository(db.GetDB())
+	verificationRepo := repositories.NewEmailVerificationRepository(db.GetDB())
+	invitationRepo := repositories.NewInvitationRepository(db.GetDB())
 	postRepo := repositories.NewPostRepository(db.GetDB())
+	revisionRepo := repositories.NewPostRevisionRepository(db.GetDB())
 	categoryRepo := repositories.NewCategoryRepository(db.GetDB())
 	tagRepo := repositories.NewTagRepository(db.GetDB())
 	commentRepo := repositories.NewCommentRepository(db.GetDB())
 	newsletterRepo := repositories.NewNewsletterRepository(db.GetDB())
 	imageRepo := repositories.NewImageRepository(db.GetDB())
+	searchRepo := repositories.NewSearchRepository(db.GetDB(), cfg.Search.Language)
+
+	transport, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	mail := mailer.NewAsync(transport)
+	switch cfg.Auth.RegistrationMode {
+	case config.RegistrationOpen, config.RegistrationInvite, config.RegistrationClosed:
+	default:
+		logger.Warn("Unknown REGISTRATION_MODE, registration is closed", map[string]any{
+			"mode": cfg.Auth.RegistrationMode,
+		})
+	}
+	// The log and file drivers keep reset, verification and invitation links
+	// where anyone who can read the logs or the disk could use them
+	if cfg.Server.Env == "production" && cfg.Mail.Driver != "smtp" {
+		logger.Warn("Emails are not being delivered and their links are kept in logs or on disk; set MAIL_DRIVER=smtp in production", map[string]any{
+			"driver": cfg.Mail.Driver,
+		})
+	}
+
+	if cfg.Server.Env == "production" && cfg.Metrics.Enabled && cfg.Metrics.Token == "" {
+		logger.Warn("Metrics are served to anyone; set METRICS_TOKEN or METRICS_ENABLED=false in production")
+	}
+
+	keys, err := services.NewKeySet(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.JWT.Secret == config.DefaultJWTSecret && strings.EqualFold(cfg.JWT.Algorithm, "HS256") {
+		logger.Warn("JWT_SECRET is not set; tokens are signed with the default secret")
+	}
+
+	responseCache, err := cache.New(cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, cfg.JWT)
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, passwordResetRepo, recoveryCodeRepo, verificationRepo, invitationRepo, mail, keys, cfg.JWT, cfg.Auth, cfg.Site)
 	userService := services.NewUserService(userRepo)
-	postService := services.NewPostService(postRepo, categoryRepo, tagRepo)
-	categoryService := services.NewCategoryService(categoryRepo)
-	tagService := services.NewTagService(tagRepo)
+	apiTokenService := services.NewAPITokenService(apiTokenRepo, userRepo)
+	invitationService := services.NewInvitationService(invitationRepo, mail, cfg.Auth, cfg.Site)
+	postService := services.NewPostService(postRepo, categoryRepo, tagRepo, revisionRepo, responseCache)
+	categoryService := services.NewCategoryService(categoryRepo, responseCache)
+	tagService := services.NewTagService(tagRepo, responseCache)
 	commentService := services.NewCommentService(commentRepo)
 	imageService := services.NewImageService(imageRepo, cfg.Upload.Path, cfg.Upload.BaseURL)
 	newsletterService := services.NewNewsletterService(newsletterRepo)
 	markdownService := services.NewMarkdownService()
+	searchService := services.NewSearchService(searchRepo)
+	feedService := services.NewFeedService(postService, categoryRepo, tagRepo, cfg.Site)
+	healthService := services.NewHealthService(sqlDB, migrator, cfg.Upload.Path)
+	metricsRegistry := metrics.NewRegistry(sqlDB)
+	metrics.RegisterGaugeFunc(metricsRegistry, "myblog_comments_pending", "Comments awaiting moderation.", func() (float64, error) {
+		pending, err := commentService.CountPending()
+		return float64(pending), err
+	})
+	sitemapService := services.NewSitemapService(postRepo, categoryRepo, tagRepo, markdownService, cfg.Site)
 
 	// Initialize handlers
+	healthHandler := handlers.NewHealthHandler(healthService)
+	metricsHandler := handlers.NewMetricsHandler(metricsRegistry, cfg.Metrics.Token)
 	authHandler := handlers.NewAuthHandler(authService)
+	jwksHandler := handlers.NewJWKSHandler(keys)
 	userHandler := handlers.NewUserHandler(userService)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
 	postHandler := handlers.NewPostHandler(postService, markdownService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
 	tagHandler := handlers.NewTagHandler(tagService)
@@ -55,25 +154,131 @@ func New(cfg *config.Config) (*App, error) {
 	newsletterHandler := handlers.NewNewsletterHandler(newsletterService)
 	imageHandler := handlers.NewImageHandler(imageService)
 	migrationHandler := handlers.NewMigrationHandler(categoryService, tagService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	feedHandler := handlers.NewFeedHandler(feedService, cfg.Site.APIURL)
+	sitemapHandler := handlers.NewSitemapHandler(sitemapService)
+	redirectHandler := handlers.NewRedirectHandler(postService, cfg.Site.URL, cfg.Site.APIURL)
+
+	// Start the scheduled post publisher
+	var publisher *services.ScheduledPublisher
+	if cfg.Publisher.Enabled {
+		publisher = services.NewScheduledPublisher(postService, time.Duration(cfg.Publisher.Interval)*time.Second)
+		publisher.Start()
+	}
+
+	limiter, err := ratelimit.New(cfg.RateLimit)
+	if err != nil {
+		return nil, err
+	}
 
 	// Setup router
-	router := setupRouter(cfg, authHandler, userHandler, postHandler, categoryHandler, tagHandler, commentHandler, newsletterHandler, imageHandler, migrationHandler)
+	router, err := setupRouter(cfg, keys, apiTokenService, limiter, healthHandler, metricsHandler, authHandler, jwksHandler, userHandler, apiTokenHandler, invitationHandler, postHandler, categoryHandler, tagHandler, commentHandler, newsletterHandler, imageHandler, migrationHandler, searchHandler, feedHandler, sitemapHandler, redirectHandler)
+	if err != nil {
+		return nil, err
+	}
 
 	return &App{
 		config: cfg,
 		router: router,
-		db:     db,
+		server: &http.Server{
+			Addr:              ":" + cfg.Server.Port,
+			Handler:           router,
+			ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout) * time.Second,
+			ReadTimeout:       time.Duration(cfg.Server.ReadTimeout) * time.Second,
+			WriteTimeout:      time.Duration(cfg.Server.WriteTimeout) * time.Second,
+			IdleTimeout:       time.Duration(cfg.Server.IdleTimeout) * time.Second,
+		},
+		db:        db,
+		mail:      mail,
+		publisher: publisher,
+		limiter:   limiter,
+		cache:     responseCache,
+		tracing:   shutdownTracing,
 	}, nil
 }
 
-func (a *App) Start() error {
-	return a.router.Run(":" + a.config.Server.Port)
+// Run serves HTTP until ctx is cancelled, then stops accepting connections and
+// gives in-flight requests up to SERVER_SHUTDOWN_TIMEOUT to finish
+func (a *App) Run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.WithService("app").Info("Shutting down, draining connections", map[string]any{
+		"timeout_seconds": a.config.Server.ShutdownTimeout,
+	})
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout())
+	defer cancel()
+	if err := a.server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain connections: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close stops the background workers, waits for queued emails, flushes traces
+// and closes the database pool. It is called once Run has returned.
+func (a *App) Close() error {
+	if a.publisher != nil {
+		a.publisher.Stop()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout())
+	defer cancel()
+	if err := a.mail.Close(ctx); err != nil {
+		logger.WithService("app").Warn("Gave up waiting for queued emails", map[string]any{
+			"error": err.Error(),
+		})
+	}
+	if err := a.limiter.Close(); err != nil {
+		logger.WithService("app").Warn("Failed to close the rate limit store", map[string]any{
+			"error": err.Error(),
+		})
+	}
+	if err := a.cache.Close(); err != nil {
+		logger.WithService("app").Warn("Failed to close the cache store", map[string]any{
+			"error": err.Error(),
+		})
+	}
+	if err := a.tracing(ctx); err != nil {
+		logger.WithService("app").Warn("Failed to flush traces", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	sqlDB, err := a.db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func (a *App) shutdownTimeout() time.Duration {
+	return time.Duration(a.config.Server.ShutdownTimeout) * time.Second
 }
 
 func setupRouter(
 	cfg *config.Config,
+	keys *services.KeySet,
+	apiTokenService services.APITokenService,
+	limiter *ratelimit.Limiter,
+	healthHandler *handlers.HealthHandler,
+	metricsHandler *handlers.MetricsHandler,
 	authHandler *handlers.AuthHandler,
+	jwksHandler *handlers.JWKSHandler,
 	userHandler *handlers.UserHandler,
+	apiTokenHandler *handlers.APITokenHandler,
+	invitationHandler *handlers.InvitationHandler,
 	postHandler *handlers.PostHandler,
 	categoryHandler *handlers.CategoryHandler,
 	tagHandler *handlers.TagHandler,
@@ -81,24 +286,74 @@ func setupRouter(
 	newsletterHandler *handlers.NewsletterHandler,
 	imageHandler *handlers.ImageHandler,
 	migrationHandler *handlers.MigrationHandler,
-) *gin.Engine {
+	searchHandler *handlers.SearchHandler,
+	feedHandler *handlers.FeedHandler,
+	sitemapHandler *handlers.SitemapHandler,
+	redirectHandler *handlers.RedirectHandler,
+) (*gin.Engine, error) {
 	if cfg.Server.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.Default()
+	authRequired := middleware.AuthRequired(keys, apiTokenService)
+
+	// Client IPs, which rate limits and login lockouts are keyed by, come from
+	// X-Forwarded-For only when the request went through a trusted proxy. With
+	// no proxies configured the header is ignored, so it cannot be forged.
+	trustedProxies := cfg.Server.TrustedProxies
+	if len(trustedProxies) == 1 && trustedProxies[0] == "none" {
+		trustedProxies = nil
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	loginLimit := middleware.RateLimit(limiter, ratelimit.GroupLogin, middleware.RateLimitByIP)
+	// Forgotten password and verification requests mail any address they are
+	// given, so they and password resets are limited per client IP
+	accountEmailLimit := middleware.RateLimit(limiter, ratelimit.GroupAccountEmail, middleware.RateLimitByIP)
 
 	// Middleware
 	router.Use(middleware.CORS(cfg.CORS))
+	router.Use(middleware.Tracing())
 	router.Use(middleware.RequestLogger())
+	if cfg.Metrics.Enabled {
+		router.Use(middleware.Metrics())
+	}
 	router.Use(middleware.ErrorLogger())
 	router.Use(middleware.Recovery())
 	router.Use(middleware.PerformanceLogger(time.Second * 5)) // Log requests that take more than 5 seconds
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Health checks: live only says the process is up, ready checks its dependencies
+	router.GET("/health", healthHandler.Live)
+	router.GET("/health/live", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
+
+	// Prometheus metrics
+	if cfg.Metrics.Enabled {
+		router.GET("/metrics", metricsHandler.GetMetrics)
+	}
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	// Syndication feeds
+	router.GET("/feed.xml", feedHandler.RSS)
+	router.GET("/atom.xml", feedHandler.Atom)
+	router.GET("/feed.json", feedHandler.JSON)
+	router.GET("/categories/:category/feed.xml", feedHandler.RSS)
+	router.GET("/categories/:category/atom.xml", feedHandler.Atom)
+	router.GET("/categories/:category/feed.json", feedHandler.JSON)
+	router.GET("/tags/:tag/feed.xml", feedHandler.RSS)
+	router.GET("/tags/:tag/atom.xml", feedHandler.Atom)
+	router.GET("/tags/:tag/feed.json", feedHandler.JSON)
+
+	// Sitemaps
+	router.GET("/sitemap.xml", sitemapHandler.GetSitemap)
+	router.GET("/sitemaps/:page", sitemapHandler.GetSitemapPage)
+
+	// Permalinks to the blog frontend, redirecting renamed posts
+	router.GET("/blog/:slug", redirectHandler.Post)
 
 	// API routes
 	api := router.Group("/api/v1")
@@ -106,9 +361,26 @@ func setupRouter(
 		// Public routes
 		auth := api.Group("/auth")
 		{
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", loginLimit, authHandler.Login)
+			auth.POST("/register", middleware.RateLimit(limiter, ratelimit.GroupRegister, middleware.RateLimitByIP), authHandler.Register)
 			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/resend-verification", accountEmailLimit, authHandler.ResendVerification)
+			auth.POST("/forgot-password", accountEmailLimit, authHandler.ForgotPassword)
+			auth.POST("/reset-password", accountEmailLimit, authHandler.ResetPassword)
+			auth.POST("/logout-all", authRequired, middleware.SessionRequired(), authHandler.LogoutAll)
+			auth.POST("/login/2fa", loginLimit, authHandler.LoginTwoFactor)
+
+			// Setup and confirmation also accept the challenge of a login
+			// that requires enrolling first
+			twoFactor := auth.Group("/2fa")
+			{
+				twoFactor.POST("/setup", middleware.TwoFactorEnrollment(keys), authHandler.SetupTwoFactor)
+				twoFactor.POST("/confirm", middleware.TwoFactorEnrollment(keys), authHandler.ConfirmTwoFactor)
+				twoFactor.POST("/disable", authRequired, middleware.SessionRequired(), authHandler.DisableTwoFactor)
+				twoFactor.POST("/recovery-codes", authRequired, middleware.SessionRequired(), authHandler.RegenerateRecoveryCodes)
+			}
 		}
 
 		// Public blog routes
@@ -118,62 +390,85 @@ func setupRouter(
 			public.GET("/posts/:slug", postHandler.GetPostBySlug)
 			public.GET("/categories", categoryHandler.GetCategories)
 			public.GET("/tags", tagHandler.GetTags)
-			public.POST("/comments", commentHandler.CreateComment)
+			public.GET("/search", searchHandler.Search)
+			public.POST("/comments", middleware.RateLimit(limiter, ratelimit.GroupComments, middleware.RateLimitByIP), commentHandler.CreateComment)
 			public.GET("/comments/post/:post_id", commentHandler.GetCommentsByPost)
-			public.POST("/newsletter/subscribe", newsletterHandler.Subscribe)
+			public.POST("/newsletter/subscribe", middleware.RateLimit(limiter, ratelimit.GroupNewsletter, middleware.RateLimitByIP), newsletterHandler.Subscribe)
 			public.GET("/newsletter/unsubscribe/:token", newsletterHandler.Unsubscribe)
-			// Temporary migration endpoint
-			public.POST("/seed-initial-data", migrationHandler.SeedInitialData)
 		}
 
 		// Protected routes
 		protected := api.Group("/")
-		protected.Use(middleware.AuthRequired(cfg.JWT))
+		protected.Use(authRequired)
+		protected.Use(middleware.RateLimit(limiter, ratelimit.GroupAPI, middleware.RateLimitByAPIToken))
 		{
 			// Users
 			users := protected.Group("/users")
 			{
 				users.GET("/me", userHandler.GetCurrentUser)
-				users.PUT("/me", userHandler.UpdateCurrentUser)
-				users.GET("", userHandler.GetUsers)
-				users.POST("", userHandler.CreateUser)
-				users.PUT("/:id", userHandler.UpdateUser)
-				users.DELETE("/:id", userHandler.DeleteUser)
+				users.PUT("/me", middleware.SessionRequired(), userHandler.UpdateCurrentUser)
+
+				// Personal API tokens cannot be managed with an API token
+				tokens := users.Group("/me/tokens", middleware.SessionRequired())
+				{
+					tokens.GET("", apiTokenHandler.GetTokens)
+					tokens.POST("", apiTokenHandler.CreateToken)
+					tokens.DELETE("/:id", apiTokenHandler.DeleteToken)
+				}
+
+				manageUsers := middleware.RequirePermission(models.PermManageUsers)
+				users.GET("", manageUsers, userHandler.GetUsers)
+				users.POST("", manageUsers, userHandler.CreateUser)
+				users.PUT("/:id", manageUsers, userHandler.UpdateUser)
+				users.DELETE("/:id", manageUsers, userHandler.DeleteUser)
+				users.DELETE("/:id/sessions", manageUsers, authHandler.RevokeUserSessions)
+				users.POST("/:id/unlock", manageUsers, authHandler.UnlockUser)
+				users.GET("/invitations", manageUsers, invitationHandler.GetInvitations)
+				users.POST("/invitations", manageUsers, invitationHandler.CreateInvitation)
+				users.DELETE("/invitations/:id", manageUsers, invitationHandler.DeleteInvitation)
 			}
 
 			// Posts
-			posts := protected.Group("/posts")
+			posts := protected.Group("/posts", middleware.RequireScope(models.ScopePostsRead, models.ScopePostsWrite))
 			{
+				// Ownership and write access are checked by the post service
 				posts.GET("", postHandler.GetPosts)
-				posts.POST("", postHandler.CreatePost)
+				posts.POST("", middleware.RequirePermission(models.PermCreatePosts), postHandler.CreatePost)
 				posts.GET("/:id", postHandler.GetPost)
 				posts.PUT("/:id", postHandler.UpdatePost)
 				posts.DELETE("/:id", postHandler.DeletePost)
 				posts.POST("/:id/publish", postHandler.PublishPost)
 				posts.POST("/:id/unpublish", postHandler.UnpublishPost)
+				posts.GET("/:id/revisions", postHandler.GetRevisions)
+				posts.GET("/:id/revisions/diff", postHandler.DiffRevisions)
+				posts.GET("/:id/revisions/:rev", postHandler.GetRevision)
+				posts.POST("/:id/revisions/:rev/restore", postHandler.RestoreRevision)
 				posts.POST("/preview", postHandler.PreviewMarkdown) // New markdown preview endpoint
 			}
 
 			// Categories
-			categories := protected.Group("/categories")
+			categories := protected.Group("/categories", middleware.RequirePermission(models.PermManageTaxonomy))
 			{
 				categories.POST("", categoryHandler.CreateCategory)
+				categories.GET("/:id", categoryHandler.GetCategory)
 				categories.PUT("/:id", categoryHandler.UpdateCategory)
 				categories.DELETE("/:id", categoryHandler.DeleteCategory)
 			}
 
 			// Tags
-			tags := protected.Group("/tags")
+			tags := protected.Group("/tags", middleware.RequirePermission(models.PermManageTaxonomy))
 			{
 				tags.POST("", tagHandler.CreateTag)
+				tags.GET("/:id", tagHandler.GetTag)
 				tags.PUT("/:id", tagHandler.UpdateTag)
 				tags.DELETE("/:id", tagHandler.DeleteTag)
 			}
 
 			// Comments
-			comments := protected.Group("/comments")
+			comments := protected.Group("/comments", middleware.RequirePermission(models.PermModerateComments))
 			{
 				comments.GET("", commentHandler.GetComments)
+				comments.GET("/:id", commentHandler.GetComment)
 				comments.PUT("/:id", commentHandler.UpdateComment)
 				comments.DELETE("/:id", commentHandler.DeleteComment)
 				comments.POST("/:id/approve", commentHandler.ApproveComment)
@@ -181,28 +476,35 @@ func setupRouter(
 			}
 
 			// Newsletter
-			newsletter := protected.Group("/newsletter")
+			newsletter := protected.Group("/newsletter", middleware.RequirePermission(models.PermManageNewsletter))
 			{
 				newsletter.GET("/subscribers", newsletterHandler.GetSubscribers)
 				newsletter.DELETE("/subscribers/:id", newsletterHandler.DeleteSubscriber)
 			}
 
 			// Images
-			images := protected.Group("/images")
+			images := protected.Group("/images", middleware.RequirePermission(models.PermUploadImages))
 			{
 				images.POST("/upload", imageHandler.UploadImage)
 				images.GET("/my", imageHandler.GetUserImages)
-				images.GET("/all", imageHandler.GetAllImages) // Admin only
+				images.GET("/all", middleware.RequirePermission(models.PermManageImages), imageHandler.GetAllImages)
 				images.GET("/:id", imageHandler.GetImage)
 				images.DELETE("/:id", imageHandler.DeleteImage)
 				images.POST("/avatar", imageHandler.UploadAvatar)
 				images.POST("/featured", imageHandler.UploadFeaturedImage)
 			}
+
+			// Readiness checks with their details
+			protected.GET("/health", middleware.AdminRequired(), healthHandler.ReadyDetails)
+
+			// Seeds the default categories and tags
+			protected.POST("/seed-initial-data", middleware.RequirePermission(models.PermManageTaxonomy), migrationHandler.SeedInitialData)
 		}
 	}
 
-	// Static file serving for uploads
-	router.Static("/uploads", cfg.Upload.Path)
+	// Static file serving for uploads. Every upload is stored under a new name,
+	// so the files never change.
+	router.Group("/uploads", middleware.Immutable()).Static("/", cfg.Upload.Path)
 
-	return router
+	return router, nil
 }
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// Areas of cached data. Writes invalidate a whole area at once.
+const (
+	AreaPosts      = "posts"
+	AreaCategories = "categories"
+	AreaTags       = "tags"
+)
+
+// keyPrefix namespaces the cache in a shared Redis database
+const keyPrefix = "myblog:cache:"
+
+// Store keeps cached values
+type Store interface {
+	// Get returns the value of key and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl, or until evicted when ttl is zero
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Close() error
+}
+
+// Cache keeps the results of read queries for a TTL. Every area has a
+// generation that is part of the keys of its entries: invalidating the area
+// starts a new generation, which leaves the entries of the older ones
+// unreachable until they expire. A value loaded while a write was committed
+// is therefore stored under the old generation and never served.
+type Cache struct {
+	store Store
+	ttl   time.Duration
+	loads singleflight.Group
+}
+
+// New creates a cache with the store selected by cfg.Store. When caching is
+// disabled every read goes to the database.
+func New(cfg config.CacheConfig) (*Cache, error) {
+	c := &Cache{ttl: time.Duration(cfg.TTL) * time.Second}
+	if !cfg.Enabled {
+		return c, nil
+	}
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("CACHE_TTL must be positive, got %d", cfg.TTL)
+	}
+
+	switch cfg.Store {
+	case "memory", "":
+		if cfg.Size <= 0 {
+			return nil, fmt.Errorf("CACHE_SIZE must be positive, got %d", cfg.Size)
+		}
+		c.store = NewMemoryStore(cfg.Size)
+	case "redis":
+		store, err := NewRedisStore(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		c.store = store
+	default:
+		return nil, fmt.Errorf("unknown cache store %q", cfg.Store)
+	}
+	return c, nil
+}
+
+// Enabled reports whether values are cached at all
+func (c *Cache) Enabled() bool {
+	return c != nil && c.store != nil
+}
+
+// Fetch returns the value cached under key in area, calling load and caching
+// its result on a miss. Concurrent misses of a key share a single call to
+// load, so a burst of requests for an expired entry reaches the database once.
+// Values round-trip through JSON, so every caller gets a copy of its own.
+// When the store is unavailable values are loaded without being cached.
+func Fetch[T any](ctx context.Context, c *Cache, area, key string, load func(context.Context) (T, error)) (T, error) {
+	if !c.Enabled() {
+		return load(ctx)
+	}
+
+	var value T
+	storeKey, err := c.key(ctx, area, key)
+	if err == nil {
+		var data []byte
+		var found bool
+		data, found, err = c.store.Get(ctx, storeKey)
+		if err == nil && found && json.Unmarshal(data, &value) == nil {
+			metrics.CacheRequests.WithLabelValues(area, "hit").Inc()
+			return value, nil
+		}
+	}
+	if err != nil {
+		logError("Cache store unavailable, reading from the database", area, err)
+		storeKey = ""
+	}
+	metrics.CacheRequests.WithLabelValues(area, "miss").Inc()
+
+	flight := storeKey
+	if flight == "" {
+		flight = area + ":" + key
+	}
+	shared, err, _ := c.loads.Do(flight, func() (any, error) {
+		// Other callers may be waiting on this load, so it outlives the caller
+		// that started it
+		ctx := context.WithoutCancel(ctx)
+		loaded, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(loaded)
+		if err != nil {
+			return nil, err
+		}
+		if storeKey != "" {
+			if err := c.store.Set(ctx, storeKey, data, c.ttl); err != nil {
+				logError("Failed to cache value", area, err)
+			}
+		}
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal(shared.([]byte), &value); err != nil {
+		return value, err
+	}
+	return value, nil
+}
+
+// Invalidate drops every entry of the given areas. When the store is
+// unavailable the stale entries are served until they expire.
+func (c *Cache) Invalidate(ctx context.Context, areas ...string) {
+	if !c.Enabled() {
+		return
+	}
+	// The write that triggered the invalidation has already been committed
+	ctx = context.WithoutCancel(ctx)
+	for _, area := range areas {
+		if err := c.store.Set(ctx, generationKey(area), newGeneration(), 0); err != nil {
+			logError("Failed to invalidate cache", area, err)
+		}
+	}
+}
+
+// Close releases the store
+func (c *Cache) Close() error {
+	if !c.Enabled() {
+		return nil
+	}
+	return c.store.Close()
+}
+
+// key places key under the current generation of area
+func (c *Cache) key(ctx context.Context, area, key string) (string, error) {
+	genKey := generationKey(area)
+	gen, found, err := c.store.Get(ctx, genKey)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		// A lost generation is replaced by a new one rather than restarted, so the
+		// entries of the previous generations stay unreachable
+		gen = newGeneration()
+		if err := c.store.Set(ctx, genKey, gen, 0); err != nil {
+			return "", err
+		}
+	}
+	return keyPrefix + area + ":" + string(gen) + ":" + key, nil
+}
+
+func generationKey(area string) string {
+	return keyPrefix + "generation:" + area
+}
+
+func newGeneration() []byte {
+	return []byte(rand.Text())
+}
+
+func logError(message, area string, err error) {
+	logger.WithService("cache").Error(message, map[string]any{
+		"area":  area,
+		"error": err.Error(),
+	})
+}
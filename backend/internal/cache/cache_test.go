@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+)
+
+// loader counts the loads of a value that changes with every load
+type loader struct {
+	calls atomic.Int32
+}
+
+func (l *loader) load(context.Context) (int, error) {
+	return int(l.calls.Add(1)), nil
+}
+
+// failingStore is a store that is down
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Close() error { return nil }
+
+func TestCacheFetch(t *testing.T) {
+	type step struct {
+		invalidate []string // areas to invalidate before fetching
+		advance    time.Duration
+		area, key  string
+		want       int // value returned, i.e. the load that produced it
+	}
+	tests := []struct {
+		name  string
+		size  int
+		steps []step
+	}{
+		{"cached", 10, []step{
+			{area: AreaPosts, key: "list", want: 1},
+			{area: AreaPosts, key: "list", want: 1},
+		}},
+		{"keys are separate", 10, []step{
+			{area: AreaPosts, key: "list", want: 1},
+			{area: AreaPosts, key: "page-2", want: 2},
+			{area: AreaPosts, key: "list", want: 1},
+		}},
+		{"invalidation starts a new generation", 10, []step{
+			{area: AreaPosts, key: "list", want: 1},
+			{invalidate: []string{AreaPosts}, area: AreaPosts, key: "list", want: 2},
+			{area: AreaPosts, key: "list", want: 2},
+		}},
+		{"invalidation leaves other areas alone", 10, []step{
+			{area: AreaTags, key: "list", want: 1},
+			{invalidate: []string{AreaPosts, AreaCategories}, area: AreaTags, key: "list", want: 1},
+		}},
+		{"entries expire", 10, []step{
+			{area: AreaPosts, key: "list", want: 1},
+			{advance: time.Minute, area: AreaPosts, key: "list", want: 2},
+		}},
+		{"evicted generation is replaced, not restarted", 2, []step{
+			// The store holds the posts generation and one entry
+			{area: AreaPosts, key: "list", want: 1},
+			// Fetching tags evicts both, then the posts generation is recreated
+			{area: AreaTags, key: "list", want: 2},
+			{area: AreaPosts, key: "list", want: 3},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(tt.size)
+			now := time.Unix(1000, 0)
+			store.now = func() time.Time { return now }
+			c := &Cache{store: store, ttl: time.Minute}
+			ctx := context.Background()
+			l := &loader{}
+
+			for i, step := range tt.steps {
+				now = now.Add(step.advance)
+				c.Invalidate(ctx, step.invalidate...)
+				got, err := Fetch(ctx, c, step.area, step.key, l.load)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != step.want {
+					t.Errorf("step %d: Fetch(%s, %s) = %d, want %d", i, step.area, step.key, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheFetchWithoutStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache *Cache
+	}{
+		{"disabled", &Cache{}},
+		{"nil", nil},
+		{"store down", &Cache{store: failingStore{}, ttl: time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &loader{}
+			for want := 1; want <= 2; want++ {
+				got, err := Fetch(context.Background(), tt.cache, AreaPosts, "list", l.load)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("Fetch = %d, want %d: nothing should be cached", got, want)
+				}
+			}
+			tt.cache.Invalidate(context.Background(), AreaPosts)
+		})
+	}
+}
+
+func TestCacheFetchLoadError(t *testing.T) {
+	c := &Cache{store: NewMemoryStore(10), ttl: time.Minute}
+	loadErr := errors.New("database down")
+	if _, err := Fetch(context.Background(), c, AreaPosts, "list", func(context.Context) (int, error) { return 0, loadErr }); !errors.Is(err, loadErr) {
+		t.Fatalf("err = %v, want %v", err, loadErr)
+	}
+
+	// Errors are not cached
+	l := &loader{}
+	if got, err := Fetch(context.Background(), c, AreaPosts, "list", l.load); err != nil || got != 1 {
+		t.Errorf("Fetch after an error = (%d, %v), want a fresh load", got, err)
+	}
+}
+
+func TestCacheFetchSharesConcurrentLoads(t *testing.T) {
+	c := &Cache{store: NewMemoryStore(10), ttl: time.Minute}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	load := func(context.Context) ([]string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return []string{"post"}, nil
+	}
+
+	const callers = 20
+	results := make([][]string, callers)
+	var wg sync.WaitGroup
+	fetch := func(i int) {
+		defer wg.Done()
+		value, err := Fetch(context.Background(), c, AreaPosts, "list", load)
+		if err != nil {
+			t.Error(err)
+		}
+		results[i] = value
+	}
+
+	wg.Add(callers)
+	go fetch(0)
+	<-started
+	for i := 1; i < callers; i++ {
+		go fetch(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Callers that arrive after the load finished hit the cache instead
+	if n := calls.Load(); n != 1 {
+		t.Errorf("load ran %d times, want 1", n)
+	}
+	for i, value := range results {
+		if len(value) != 1 || value[0] != "post" {
+			t.Fatalf("caller %d got %v", i, value)
+		}
+	}
+	// Every caller gets a copy of its own
+	results[0][0] = "changed"
+	if results[1][0] != "post" {
+		t.Error("callers share the loaded value")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.CacheConfig
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{"disabled", config.CacheConfig{Enabled: false}, false, false},
+		{"memory", config.CacheConfig{Enabled: true, Store: "memory", TTL: 60, Size: 10}, true, false},
+		{"zero ttl", config.CacheConfig{Enabled: true, Store: "memory", TTL: 0, Size: 10}, false, true},
+		{"zero size", config.CacheConfig{Enabled: true, Store: "memory", TTL: 60, Size: 0}, false, true},
+		{"unknown store", config.CacheConfig{Enabled: true, Store: "memcached", TTL: 60}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && c.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled = %v, want %v", c.Enabled(), tt.wantEnabled)
+			}
+		})
+	}
+}
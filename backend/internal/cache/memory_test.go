@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	type op struct {
+		advance time.Duration
+		set     bool // Set key to value with ttl, or else Get it
+		key     string
+		value   string
+		ttl     time.Duration
+		found   bool // for Get: whether value is expected
+	}
+	tests := []struct {
+		name string
+		size int
+		ops  []op
+	}{
+		{"miss", 2, []op{
+			{key: "a"},
+		}},
+		{"hit", 2, []op{
+			{set: true, key: "a", value: "1"},
+			{key: "a", value: "1", found: true},
+		}},
+		{"overwrite", 2, []op{
+			{set: true, key: "a", value: "1"},
+			{set: true, key: "a", value: "2"},
+			{key: "a", value: "2", found: true},
+		}},
+		{"expires after ttl", 2, []op{
+			{set: true, key: "a", value: "1", ttl: time.Minute},
+			{advance: time.Minute - time.Second, key: "a", value: "1", found: true},
+			{advance: time.Second, key: "a"},
+		}},
+		{"zero ttl never expires", 2, []op{
+			{set: true, key: "a", value: "1"},
+			{advance: 24 * time.Hour, key: "a", value: "1", found: true},
+		}},
+		{"overwrite resets ttl", 2, []op{
+			{set: true, key: "a", value: "1", ttl: time.Minute},
+			{advance: 50 * time.Second, set: true, key: "a", value: "2", ttl: time.Minute},
+			{advance: 50 * time.Second, key: "a", value: "2", found: true},
+		}},
+		{"evicts least recently set", 2, []op{
+			{set: true, key: "a", value: "1"},
+			{set: true, key: "b", value: "2"},
+			{set: true, key: "c", value: "3"},
+			{key: "a"},
+			{key: "b", value: "2", found: true},
+			{key: "c", value: "3", found: true},
+		}},
+		{"get refreshes recency", 2, []op{
+			{set: true, key: "a", value: "1"},
+			{set: true, key: "b", value: "2"},
+			{key: "a", value: "1", found: true},
+			{set: true, key: "c", value: "3"},
+			{key: "b"},
+			{key: "a", value: "1", found: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(tt.size)
+			now := time.Unix(1000, 0)
+			store.now = func() time.Time { return now }
+			ctx := context.Background()
+
+			for i, op := range tt.ops {
+				now = now.Add(op.advance)
+				if op.set {
+					if err := store.Set(ctx, op.key, []byte(op.value), op.ttl); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				value, found, err := store.Get(ctx, op.key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if found != op.found || (found && string(value) != op.value) {
+					t.Errorf("op %d: Get(%q) = (%q, %v), want (%q, %v)", i, op.key, value, found, op.value, op.found)
+				}
+			}
+			if len(store.entries) > tt.size || store.order.Len() != len(store.entries) {
+				t.Errorf("store holds %d entries and %d list elements, size %d", len(store.entries), store.order.Len(), tt.size)
+			}
+		})
+	}
+}
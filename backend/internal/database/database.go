@@ -1,13 +1,15 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 
 	"github.com/chmenegatti/myBlog/internal/config"
-	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/migrations"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -18,6 +20,56 @@ type DB struct {
 }
 
 func New(cfg config.DatabaseConfig) (*DB, error) {
+	db, err := gorm.Open(postgres.Open(DSN(cfg)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := db.Use(metricsPlugin{}); err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracingPlugin{}); err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		if err := Migrate(context.Background(), sqlDB); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	return &DB{db}, nil
+}
+
+// Migrator returns a migrator for the embedded migrations
+func (db *DB) Migrator() (*Migrator, error) {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(sqlDB, migrations.FS)
+}
+
+// Migrate applies the pending embedded migrations
+func Migrate(ctx context.Context, db *sql.DB) error {
+	migrator, err := NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+	return err
+}
+
+// DSN returns the connection string for the database
+func DSN(cfg config.DatabaseConfig) string {
 	var dsn string
 
 	// Priority order for database connection:
@@ -38,28 +90,7 @@ func New(cfg config.DatabaseConfig) (*DB, error) {
 			cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode)
 		log.Printf("Using individual database config (development mode)")
 	}
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
-	}
-
-	// Auto migrate tables
-	if err := db.AutoMigrate(
-		&models.User{},
-		&models.Post{},
-		&models.Category{},
-		&models.Tag{},
-		&models.Comment{},
-		&models.Newsletter{},
-		&models.Image{},
-	); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
-	}
-
-	return &DB{db}, nil
+	return dsn
 }
 
 func (db *DB) GetDB() *gorm.DB {
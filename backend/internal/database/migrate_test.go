@@ -0,0 +1,370 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/chmenegatti/myBlog/migrations"
+)
+
+func checksum(up string) string {
+	sum := sha256.Sum256([]byte(up))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "sorted by version",
+			files: fstest.MapFS{
+				"0010_add_tags.up.sql":         {Data: []byte("CREATE TABLE tags ();")},
+				"0002_add_posts.up.sql":        {Data: []byte("CREATE TABLE posts ();")},
+				"0002_add_posts.down.sql":      {Data: []byte("DROP TABLE posts;")},
+				"0001_initial_schema.up.sql":   {Data: []byte("CREATE TABLE users ();")},
+				"README.md":                    {Data: []byte("not a migration")},
+				"fixtures/0003_ignored.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "initial_schema", Up: "CREATE TABLE users ();", Checksum: checksum("CREATE TABLE users ();")},
+				{Version: 2, Name: "add_posts", Up: "CREATE TABLE posts ();", Down: "DROP TABLE posts;", Checksum: checksum("CREATE TABLE posts ();")},
+				{Version: 10, Name: "add_tags", Up: "CREATE TABLE tags ();", Checksum: checksum("CREATE TABLE tags ();")},
+			},
+		},
+		{
+			name:  "empty",
+			files: fstest.MapFS{},
+			want:  []Migration{},
+		},
+		{
+			name:    "invalid file name",
+			files:   fstest.MapFS{"0001_Initial.up.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: `invalid migration file name "0001_Initial.up.sql"`,
+		},
+		{
+			name:    "no version",
+			files:   fstest.MapFS{"initial.up.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: "invalid migration file name",
+		},
+		{
+			name: "shared version",
+			files: fstest.MapFS{
+				"0001_users.up.sql": {Data: []byte("SELECT 1;")},
+				"0001_posts.up.sql": {Data: []byte("SELECT 2;")},
+			},
+			wantErr: "share version 1",
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"0001_users.down.sql": {Data: []byte("DROP TABLE users;")}},
+			wantErr: "migration 1_users has no up file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMigrations(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d migrations, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("migration %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// expectLocked expects the statements Migrator.locked runs before fn
+func expectLocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).
+		WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).
+		WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, applied ...appliedMigration) {
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	for _, record := range applied {
+		rows.AddRow(record.version, record.name, record.checksum, record.appliedAt)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, name, checksum, applied_at FROM schema_migrations`)).
+		WillReturnRows(rows)
+}
+
+func expectApply(mock sqlmock.Sqlmock, migration Migration, execErr error) {
+	mock.ExpectBegin()
+	if execErr != nil {
+		mock.ExpectExec(regexp.QuoteMeta(migration.Up)).WillReturnError(execErr)
+		mock.ExpectRollback()
+		return
+	}
+	mock.ExpectExec(regexp.QuoteMeta(migration.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`)).
+		WithArgs(migration.Version, migration.Name, migration.Checksum).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestMigratorUp(t *testing.T) {
+	first := Migration{Version: 1, Name: "initial_schema", Up: "CREATE TABLE users ();", Checksum: checksum("CREATE TABLE users ();")}
+	second := Migration{Version: 2, Name: "add_posts", Up: "CREATE TABLE posts ();", Checksum: checksum("CREATE TABLE posts ();")}
+	appliedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expect   func(mock sqlmock.Sqlmock)
+		wantDone []int64
+		wantErr  string
+	}{
+		{
+			name: "applies pending migrations in order",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock)
+				expectApplied(mock)
+				expectApply(mock, first, nil)
+				expectApply(mock, second, nil)
+				expectUnlock(mock)
+			},
+			wantDone: []int64{1, 2},
+		},
+		{
+			name: "skips applied migrations",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock)
+				expectApplied(mock, appliedMigration{1, first.Name, first.Checksum, appliedAt})
+				expectApply(mock, second, nil)
+				expectUnlock(mock)
+			},
+			wantDone: []int64{2},
+		},
+		{
+			name: "nothing pending",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock)
+				expectApplied(mock,
+					appliedMigration{1, first.Name, first.Checksum, appliedAt},
+					appliedMigration{2, second.Name, second.Checksum, appliedAt})
+				expectUnlock(mock)
+			},
+		},
+		{
+			name: "refuses a modified migration",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock)
+				expectApplied(mock, appliedMigration{1, first.Name, checksum("CREATE TABLE people ();"), appliedAt})
+				expectUnlock(mock)
+			},
+			wantErr: "migration 1_initial_schema was modified after it was applied",
+		},
+		{
+			name: "stops at a failing migration",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectLocked(mock)
+				expectApplied(mock)
+				expectApply(mock, first, nil)
+				expectApply(mock, second, errors.New("syntax error"))
+				expectUnlock(mock)
+			},
+			wantDone: []int64{1},
+			wantErr:  "migration 2_add_posts failed: syntax error",
+		},
+		{
+			name: "lock not acquired",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).
+					WithArgs(migrationLockKey).WillReturnError(errors.New("connection reset"))
+			},
+			wantErr: "failed to acquire migration lock",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			tt.expect(mock)
+
+			m := &Migrator{db: db, migrations: []Migration{first, second}}
+			done, err := m.Up(context.Background())
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+
+			var versions []int64
+			for _, migration := range done {
+				versions = append(versions, migration.Version)
+			}
+			if len(versions) != len(tt.wantDone) {
+				t.Fatalf("applied %v, want %v", versions, tt.wantDone)
+			}
+			for i := range versions {
+				if versions[i] != tt.wantDone[i] {
+					t.Fatalf("applied %v, want %v", versions, tt.wantDone)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMigratorDown(t *testing.T) {
+	first := Migration{Version: 1, Name: "initial_schema", Up: "CREATE TABLE users ();", Checksum: checksum("CREATE TABLE users ();")}
+	second := Migration{Version: 2, Name: "add_posts", Up: "CREATE TABLE posts ();", Down: "DROP TABLE posts;", Checksum: checksum("CREATE TABLE posts ();")}
+	appliedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expectRollback := func(mock sqlmock.Sqlmock, migration Migration) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migration.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM schema_migrations WHERE version = $1`)).
+			WithArgs(migration.Version).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	tests := []struct {
+		name     string
+		steps    int
+		expect   func(mock sqlmock.Sqlmock)
+		wantDone int
+		wantErr  error
+	}{
+		{
+			name:  "rolls back the newest",
+			steps: 1,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRollback(mock, second)
+			},
+			wantDone: 1,
+		},
+		{
+			name:  "stops at a migration without down file",
+			steps: 99,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRollback(mock, second)
+			},
+			wantDone: 1,
+			wantErr:  ErrIrreversibleMigration,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			expectLocked(mock)
+			expectApplied(mock,
+				appliedMigration{1, first.Name, first.Checksum, appliedAt},
+				appliedMigration{2, second.Name, second.Checksum, appliedAt})
+			tt.expect(mock)
+			expectUnlock(mock)
+
+			m := &Migrator{db: db, migrations: []Migration{first, second}}
+			done, err := m.Down(context.Background(), tt.steps)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(done) != tt.wantDone {
+				t.Errorf("rolled back %d migrations, want %d", len(done), tt.wantDone)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestInitialMigrationIsIrreversible(t *testing.T) {
+	all, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 || all[0].Version != 1 {
+		t.Fatal("migration 0001 is missing")
+	}
+	if all[0].Down != "" {
+		t.Error("0001_initial_schema adopts existing databases and must not have a down file")
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		migName  string
+		want     []string
+		wantErr  bool
+	}{
+		{"first", nil, "initial_schema", []string{"0001_initial_schema.up.sql", "0001_initial_schema.down.sql"}, false},
+		{"after the highest version", []string{"0001_a.up.sql", "0007_b.up.sql"}, "add_tags", []string{"0008_add_tags.up.sql", "0008_add_tags.down.sql"}, false},
+		{"bad name", nil, "Add-Tags", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			paths, err := CreateMigration(dir, tt.migName)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != len(tt.want) {
+				t.Fatalf("created %v, want %v", paths, tt.want)
+			}
+			for i, path := range paths {
+				if filepath.Base(path) != tt.want[i] {
+					t.Errorf("created %s, want %s", filepath.Base(path), tt.want[i])
+				}
+			}
+			if _, err := LoadMigrations(os.DirFS(dir)); err != nil {
+				t.Errorf("created files do not load: %v", err)
+			}
+		})
+	}
+}
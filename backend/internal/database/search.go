@@ -0,0 +1,27 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckSearchLanguage verifies that the full-text search column on posts,
+// created by the migrations, uses the text search configuration queries are
+// run with. Searching with another configuration would silently miss matches.
+// A table or column not created yet, e.g. on a fresh database with
+// DB_AUTO_MIGRATE=false, is left to the pending migrations.
+func (db *DB) CheckSearchLanguage(language string) error {
+	var expression string
+	if err := db.Raw(`SELECT COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a
+		JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = to_regclass('posts') AND a.attname = 'search_vector' AND NOT a.attisdropped`).
+		Scan(&expression).Error; err != nil {
+		return fmt.Errorf("failed to inspect search column: %w", err)
+	}
+
+	if expression != "" && !strings.Contains(expression, "'"+language+"'::regconfig") {
+		return fmt.Errorf("posts.search_vector was not built with SEARCH_LANGUAGE %q; add a migration that rebuilds it", language)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chmenegatti/myBlog/internal/middleware"
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APITokenHandler manages the current user's personal API tokens
+type APITokenHandler struct {
+	apiTokenService services.APITokenService
+}
+
+func NewAPITokenHandler(apiTokenService services.APITokenService) *APITokenHandler {
+	return &APITokenHandler{apiTokenService: apiTokenService}
+}
+
+func (h *APITokenHandler) GetTokens(c *gin.Context) {
+	actor, _ := currentActor(c)
+
+	tokens, err := h.apiTokenService.List(actor.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"total":  len(tokens),
+	})
+}
+
+// CreateToken issues a token. The response is the only time the plain token
+// is shown.
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	var req services.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, _ := currentActor(c)
+
+	token, raw, err := h.apiTokenService.Create(actor.UserID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+		return
+	}
+
+	middleware.LogSecurityEvent("api_token_created", "api_token:"+token.ID.String(), actor.UserID.String(), token.Name, "low")
+	c.JSON(http.StatusCreated, gin.H{
+		"token":     raw,
+		"api_token": token,
+	})
+}
+
+func (h *APITokenHandler) DeleteToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	actor, _ := currentActor(c)
+
+	if err := h.apiTokenService.Revoke(actor.UserID, id); err != nil {
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+		return
+	}
+
+	middleware.LogSecurityEvent("api_token_revoked", "api_token:"+id.String(), actor.UserID.String(), "revoked by user", "low")
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/chmenegatti/myBlog/internal/middleware"
+	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/chmenegatti/myBlog/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -31,9 +35,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(req.Email, req.Password)
+	response, err := h.authService.Login(req.Email, req.Password, clientInfo(c))
 	if err != nil {
 		middleware.LogAuthAttempt(req.Email, "login", false, err.Error())
+		if respondLoginLocked(c, req.Email, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
@@ -54,6 +61,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	user, err := h.authService.Register(&req)
 	if err != nil {
 		middleware.LogAuthAttempt(req.Email, "register", false, err.Error())
+		if errors.Is(err, services.ErrRegistrationClosed) || errors.Is(err, services.ErrInvitationRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -66,7 +77,70 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, user)
 }
 
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req refreshTokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.RefreshToken(req.RefreshToken, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			middleware.LogSecurityEvent("refresh_token_reuse", "session", "", "rotated refresh token presented again, session revoked", "high")
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout ends the session of the given refresh token
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req refreshTokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.Logout(req.RefreshToken); err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll ends every session of the authenticated user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if err := h.authService.LogoutAll(userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	middleware.LogBusinessOperation("logout_all", userID.(uuid.UUID).String(), nil)
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions have been revoked"})
+}
+
+// VerifyEmail activates a registered account with the emailed token
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	var req struct {
 		Token string `json:"token" binding:"required"`
 	}
@@ -76,15 +150,315 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.RefreshToken(req.Token)
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified, you can now log in"})
+}
+
+// ResendVerification emails a new verification link. It answers the same way
+// whether or not the account exists or is waiting for verification.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResendVerification(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account is waiting for verification, a new link has been sent"})
+}
+
+// ForgotPassword emails a password reset link. It answers the same way
+// whether or not the email is registered.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password reset request"})
+		return
+	}
+
+	middleware.LogAuthAttempt(req.Email, "forgot_password", true, "")
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+}
+
+// LoginTwoFactor completes a login that answered with two_factor_required
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+		return
+	}
+
+	response, err := h.authService.LoginTwoFactor(req.ChallengeToken, req.Code, req.RecoveryCode, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		middleware.LogAuthAttempt("", "login_2fa", false, err.Error())
+		if respondLoginLocked(c, "", err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidChallenge) || errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+
+	middleware.LogAuthAttempt(response.User.Email, "login_2fa", true, "")
+	c.JSON(http.StatusOK, response)
+}
+
+// SetupTwoFactor starts TOTP enrollment and returns the secret to add to an
+// authenticator app
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	actor, _ := currentActor(c)
+
+	setup, err := h.authService.SetupTOTP(actor.UserID)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmTwoFactor enables TOTP with a first code from the authenticator app.
+// The recovery codes in the response are only ever shown once. When the user
+// was enrolling during login, the response also carries the session tokens.
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	actor, _ := currentActor(c)
+
+	codes, err := h.authService.ConfirmTOTP(actor.UserID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTwoFactorCode), errors.Is(err, services.ErrTwoFactorNotSetUp):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		}
+		return
+	}
+
+	middleware.LogBusinessOperation("two_factor_enabled", actor.UserID.String(), nil)
+
+	response := gin.H{"recovery_codes": codes}
+	if c.GetBool("two_factor_enrollment") {
+		session, err := h.authService.LoginAfterEnrollment(actor.UserID, clientInfo(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+			return
+		}
+		response["session"] = session
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, _ := currentActor(c)
+
+	if err := h.authService.DisableTOTP(actor.UserID, req.Password, req.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorRequired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTwoFactorNotEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	middleware.LogSecurityEvent("two_factor_disabled", "user:"+actor.UserID.String(), actor.UserID.String(), "disabled by user", "medium")
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, _ := currentActor(c)
+
+	codes, err := h.authService.RegenerateRecoveryCodes(actor.UserID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorNotEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RevokeUserSessions lets an admin end every session of another user, e.g.
+// after their credentials leaked
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.authService.LogoutAll(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	middleware.LogSecurityEvent("sessions_revoked", "user:"+id.String(), fmt.Sprint(adminID), "revoked by admin", "medium")
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions have been revoked"})
+}
+
+// UnlockUser lets an admin lift a lockout caused by failed logins
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.authService.UnlockAccount(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	middleware.LogSecurityEvent("account_unlocked", "user:"+id.String(), fmt.Sprint(adminID), "unlocked by admin", "medium")
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+// respondLoginLocked answers 429 with Retry-After when err is a login lockout,
+// emitting a security event when the attempt caused the lockout
+func respondLoginLocked(c *gin.Context, email string, err error) bool {
+	var locked *services.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	if locked.JustLocked {
+		resource := "ip:" + c.ClientIP()
+		if locked.Scope == "account" && email != "" {
+			resource = "account:" + email
+		}
+		middleware.LogSecurityEvent("login_lockout", resource, "", "locked for "+locked.RetryAfter.String()+" after repeated failed logins", "high")
+	}
+
+	seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       err.Error(),
+		"retry_after": seconds,
+	})
+	return true
+}
+
+// currentActor returns the user authenticated by middleware.AuthRequired
+func currentActor(c *gin.Context) (services.Actor, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return services.Actor{}, false
+	}
+	role, _ := c.Get("user_role")
+	userRole, _ := role.(models.UserRole)
+	return services.Actor{UserID: userID.(uuid.UUID), Role: userRole, Token: middleware.APIToken(c)}, true
+}
+
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
 // User Handler
 type UserHandler struct {
 	userService services.UserService
@@ -197,10 +571,11 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	var req struct {
-		Name     string `json:"name"`
-		Bio      string `json:"bio"`
-		Avatar   string `json:"avatar"`
-		IsActive *bool  `json:"is_active"`
+		Name     string          `json:"name"`
+		Bio      string          `json:"bio"`
+		Avatar   string          `json:"avatar"`
+		IsActive *bool           `json:"is_active"`
+		Role     models.UserRole `json:"role"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -208,6 +583,18 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if req.Role != "" && !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidRole.Error()})
+		return
+	}
+
+	// Admins cannot lock themselves out
+	if actor, _ := currentActor(c); actor.UserID == id &&
+		((req.Role != "" && req.Role != actor.Role) || (req.IsActive != nil && !*req.IsActive)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role or deactivate yourself"})
+		return
+	}
+
 	if req.Name != "" {
 		user.Name = req.Name
 	}
@@ -216,6 +603,9 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	if req.IsActive != nil {
 		user.IsActive = *req.IsActive
 	}
+	if req.Role != "" {
+		user.Role = req.Role
+	}
 
 	if err := h.userService.Update(user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
@@ -232,6 +622,11 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	if actor, _ := currentActor(c); actor.UserID == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete yourself"})
+		return
+	}
+
 	if err := h.userService.Delete(id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Cache-Control of the public read routes. Once max-age runs out clients
+// revalidate with the ETag, which costs a 304 when nothing changed.
+const (
+	cacheListings = "public, max-age=60"
+	cachePost     = "public, max-age=300"
+	cacheTaxonomy = "public, max-age=300"
+	cacheFeed     = "public, max-age=900"
+	cacheSitemap  = "public, max-age=3600"
+)
+
+// respondCacheable writes body with a strong ETag computed from its content
+// and the given Cache-Control, or 304 Not Modified when the client already
+// holds it. Last-Modified is sent unless lastModified is zero.
+func respondCacheable(c *gin.Context, contentType string, body []byte, lastModified time.Time, cacheControl string) {
+	respondWithETag(c, contentType, body, hashETag(body), lastModified, cacheControl)
+}
+
+// respondWithETag is respondCacheable with an ETag computed by the caller
+func respondWithETag(c *gin.Context, contentType string, body []byte, etag string, lastModified time.Time, cacheControl string) {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// respondCacheableJSON encodes value the way c.JSON does and writes it with
+// respondCacheable, or with etag when it is not empty
+func respondCacheableJSON(c *gin.Context, value any, etag string, lastModified time.Time, cacheControl string) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	if etag == "" {
+		etag = hashETag(body)
+	}
+	respondWithETag(c, "application/json; charset=utf-8", body, etag, lastModified, cacheControl)
+}
+
+// hashETag is a strong entity tag for content
+func hashETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:18]) + `"`
+}
+
+// postsETag identifies posts by their IDs, versions and the last change to
+// what they embed, so the view count, which every view of a post changes, does
+// not change the tag. extra distinguishes the rest of the representation,
+// e.g. the page of a listing.
+func postsETag(extra string, posts ...*models.Post) string {
+	var b strings.Builder
+	b.WriteString(extra)
+	for _, post := range posts {
+		fmt.Fprintf(&b, "|%s:%d:%d", post.ID, post.Version, postLastModified(post).UnixNano())
+	}
+	return hashETag([]byte(b.String()))
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is
+// none, as RFC 9110 orders the two
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if values := r.Header.Values("If-None-Match"); len(values) > 0 {
+		return etagMatches(strings.Join(values, ","), etag)
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified only has a resolution of seconds
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches applies the weak comparison If-None-Match calls for to a list
+// of entity tags
+func etagMatches(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// postLastModified is the last change to a post or to the author, categories
+// and tags embedded in it
+func postLastModified(post *models.Post) time.Time {
+	last := post.UpdatedAt
+	if post.Author.UpdatedAt.After(last) {
+		last = post.Author.UpdatedAt
+	}
+	for _, category := range post.Categories {
+		if category.UpdatedAt.After(last) {
+			last = category.UpdatedAt
+		}
+	}
+	for _, tag := range post.Tags {
+		if tag.UpdatedAt.After(last) {
+			last = tag.UpdatedAt
+		}
+	}
+	return last
+}
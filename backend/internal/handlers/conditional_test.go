@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestPostsETag(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	base := func() *models.Post {
+		return &models.Post{
+			ID:         uuid.MustParse("6f1c1a52-8d9e-4a1b-9a55-0c1f3a2b4c5d"),
+			Version:    3,
+			UpdatedAt:  updated,
+			ViewCount:  10,
+			Categories: []models.Category{{Name: "Go", UpdatedAt: updated}},
+		}
+	}
+	tag := postsETag("", base())
+
+	tests := []struct {
+		name   string
+		change func(post *models.Post)
+		same   bool
+	}{
+		{"view count", func(p *models.Post) { p.ViewCount++ }, true},
+		{"version", func(p *models.Post) { p.Version++ }, false},
+		{"updated", func(p *models.Post) { p.UpdatedAt = p.UpdatedAt.Add(time.Second) }, false},
+		{"category renamed", func(p *models.Post) { p.Categories[0].UpdatedAt = updated.Add(time.Minute) }, false},
+		{"author edited", func(p *models.Post) { p.Author.UpdatedAt = updated.Add(time.Minute) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := base()
+			tt.change(post)
+			if got := postsETag("", post) == tag; got != tt.same {
+				t.Errorf("ETag unchanged = %v, want %v", got, tt.same)
+			}
+		})
+	}
+
+	if postsETag("10:10:0", base()) == postsETag("10:10:10", base()) {
+		t.Error("listing pages share an ETag")
+	}
+}
+
+func TestRespondCacheableJSON(t *testing.T) {
+	post := &models.Post{ID: uuid.New(), Version: 1, ViewCount: 1}
+	etag := postsETag("", post)
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		viewCount   int
+		wantStatus  int
+	}{
+		{"first request", "", 1, http.StatusOK},
+		{"same post", etag, 1, http.StatusNotModified},
+		{"viewed since", etag, 42, http.StatusNotModified},
+		{"weak match", "W/" + etag, 1, http.StatusNotModified},
+		{"list match", `"other", ` + etag, 1, http.StatusNotModified},
+		{"other tag", `"other"`, 1, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.ifNoneMatch != "" {
+				c.Request.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			viewed := *post
+			viewed.ViewCount = tt.viewCount
+			respondCacheableJSON(c, &viewed, postsETag("", &viewed), time.Time{}, cachePost)
+			c.Writer.WriteHeaderNow()
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %q, want %q", got, etag)
+			}
+		})
+	}
+}
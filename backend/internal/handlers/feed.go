@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Feed Handler
+type FeedHandler struct {
+	feedService services.FeedService
+	apiURL      string
+}
+
+func NewFeedHandler(feedService services.FeedService, apiURL string) *FeedHandler {
+	return &FeedHandler{feedService: feedService, apiURL: apiURL}
+}
+
+func (h *FeedHandler) RSS(c *gin.Context) {
+	h.render(c, "application/rss+xml; charset=utf-8", h.feedService.RSS)
+}
+
+func (h *FeedHandler) Atom(c *gin.Context) {
+	h.render(c, "application/atom+xml; charset=utf-8", h.feedService.Atom)
+}
+
+func (h *FeedHandler) JSON(c *gin.Context) {
+	h.render(c, "application/feed+json; charset=utf-8", h.feedService.JSON)
+}
+
+// render builds the feed for the route (main, category or tag) and encodes it
+func (h *FeedHandler) render(c *gin.Context, contentType string, encode func(*services.Feed, string) ([]byte, error)) {
+	scope := services.FeedScope{
+		CategorySlug: c.Param("category"),
+		TagSlug:      c.Param("tag"),
+	}
+
+	feed, err := h.feedService.GetFeed(c.Request.Context(), scope)
+	if err != nil {
+		if errors.Is(err, services.ErrFeedNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feed"})
+		return
+	}
+
+	body, err := encode(feed, h.apiURL+c.Request.URL.Path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feed"})
+		return
+	}
+
+	respondCacheable(c, contentType, body, time.Time{}, cacheFeed)
+}
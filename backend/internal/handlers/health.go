@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Health Handler
+type HealthHandler struct {
+	healthService services.HealthService
+}
+
+func NewHealthHandler(healthService services.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+// Live reports that the process is up and serving requests. It checks no
+// dependencies, so a database outage does not get the instance restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": services.HealthOK})
+}
+
+// Ready reports whether the instance can serve traffic, answering 503 when
+// any dependency check fails. The probe is public, so it only tells the
+// status; failed checks are logged, and admins can see them in ReadyDetails.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.healthService.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != services.HealthOK {
+		status = http.StatusServiceUnavailable
+		for name, check := range report.Checks {
+			if check.Status != services.HealthOK {
+				logger.Warn("Readiness check failed", map[string]any{
+					"check":   name,
+					"error":   check.Error,
+					"details": check.Details,
+				})
+			}
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"status": report.Status})
+}
+
+// ReadyDetails runs the readiness checks like Ready and answers with the
+// result of each one, pool statistics and errors included
+func (h *HealthHandler) ReadyDetails(c *gin.Context) {
+	report := h.healthService.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != services.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
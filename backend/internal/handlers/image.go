@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -82,7 +83,7 @@ func (h *ImageHandler) GetUserImages(c *gin.Context) {
 	})
 }
 
-// GetAllImages returns all images (admin only)
+// GetAllImages returns all images (requires images:manage)
 func (h *ImageHandler) GetAllImages(c *gin.Context) {
 	// Parse pagination parameters
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
@@ -122,7 +123,7 @@ func (h *ImageHandler) GetImage(c *gin.Context) {
 
 // DeleteImage deletes an image
 func (h *ImageHandler) DeleteImage(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	actor, exists := currentActor(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
@@ -135,9 +136,9 @@ func (h *ImageHandler) DeleteImage(c *gin.Context) {
 		return
 	}
 
-	err = h.imageService.DeleteImage(id, userID.(uuid.UUID))
+	err = h.imageService.DeleteImage(id, actor)
 	if err != nil {
-		if err.Error() == "unauthorized to delete this image" {
+		if errors.Is(err, services.ErrForbidden) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/chmenegatti/myBlog/internal/middleware"
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InvitationHandler lets admins invite people to register
+type InvitationHandler struct {
+	invitationService services.InvitationService
+}
+
+func NewInvitationHandler(invitationService services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{invitationService: invitationService}
+}
+
+func (h *InvitationHandler) GetInvitations(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	invitations, total, err := h.invitationService.List(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// CreateInvitation returns the registration link, which is not stored and
+// cannot be shown again
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req services.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, _ := currentActor(c)
+
+	invitation, link, err := h.invitationService.Create(actor.UserID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	middleware.LogBusinessOperation("invitation_created", actor.UserID.String(), map[string]any{
+		"invitation_id": invitation.ID.String(),
+		"email":         invitation.Email,
+		"role":          invitation.Role,
+	})
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+		"url":        link,
+	})
+}
+
+func (h *InvitationHandler) DeleteInvitation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.invitationService.Revoke(id); err != nil {
+		if errors.Is(err, services.ErrInvitationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
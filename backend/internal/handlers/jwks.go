@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS Handler
+type JWKSHandler struct {
+	keys *services.KeySet
+}
+
+func NewJWKSHandler(keys *services.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS publishes the public keys access tokens can be verified with. The
+// same keys sign two-factor challenges, so verifiers must also check that iss
+// is JWT_ISSUER and aud is services.AudienceSession.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
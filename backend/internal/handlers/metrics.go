@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics Handler
+type MetricsHandler struct {
+	handler http.Handler
+	token   string
+}
+
+// NewMetricsHandler serves the registry. When token is set, scrapers must
+// send it as a bearer token.
+func NewMetricsHandler(registry *prometheus.Registry, token string) *MetricsHandler {
+	return &MetricsHandler{
+		handler: promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+		token:   token,
+	}
+}
+
+// GetMetrics renders the metrics in the Prometheus exposition format
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	if h.token != "" {
+		expected := "Bearer " + h.token
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+	}
+
+	h.handler.ServeHTTP(c.Writer, c.Request)
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/chmenegatti/myBlog/internal/services"
@@ -43,7 +45,23 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, categories)
+	respondCacheableJSON(c, categories, "", time.Time{}, cacheTaxonomy)
+}
+
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	category, err := h.categoryService.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	respondVersioned(c, http.StatusOK, category, category.Version)
 }
 
 func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
@@ -63,6 +81,7 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		Name        string `json:"name"`
 		Description string `json:"description"`
 		Color       string `json:"color"`
+		Version     int    `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -70,6 +89,12 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		return
 	}
 
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+	category.Version = version
+
 	if req.Name != "" {
 		category.Name = req.Name
 	}
@@ -79,11 +104,20 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 	}
 
 	if err := h.categoryService.Update(category); err != nil {
+		if errors.Is(err, services.ErrVersionConflict) {
+			current, err := h.categoryService.GetByID(id)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+				return
+			}
+			respondVersionConflict(c, "Category", current, current.Version)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
 		return
 	}
 
-	c.JSON(http.StatusOK, category)
+	respondVersioned(c, http.StatusOK, category, category.Version)
 }
 
 func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
@@ -268,7 +302,23 @@ func (h *TagHandler) GetTags(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, tags)
+	respondCacheableJSON(c, tags, "", time.Time{}, cacheTaxonomy)
+}
+
+func (h *TagHandler) GetTag(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	tag, err := h.tagService.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	respondVersioned(c, http.StatusOK, tag, tag.Version)
 }
 
 func (h *TagHandler) UpdateTag(c *gin.Context) {
@@ -285,7 +335,8 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 	}
 
 	var req struct {
-		Name string `json:"name"`
+		Name    string `json:"name"`
+		Version int    `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -293,16 +344,31 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 		return
 	}
 
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+	tag.Version = version
+
 	if req.Name != "" {
 		tag.Name = req.Name
 	}
 
 	if err := h.tagService.Update(tag); err != nil {
+		if errors.Is(err, services.ErrVersionConflict) {
+			current, err := h.tagService.GetByID(id)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+				return
+			}
+			respondVersionConflict(c, "Tag", current, current.Version)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
 		return
 	}
 
-	c.JSON(http.StatusOK, tag)
+	respondVersioned(c, http.StatusOK, tag, tag.Version)
 }
 
 func (h *TagHandler) DeleteTag(c *gin.Context) {
@@ -364,6 +430,22 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 	})
 }
 
+func (h *CommentHandler) GetComment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	comment, err := h.commentService.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	respondVersioned(c, http.StatusOK, comment, comment.Version)
+}
+
 func (h *CommentHandler) UpdateComment(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -379,6 +461,7 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 
 	var req struct {
 		Content string `json:"content"`
+		Version int    `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -386,16 +469,31 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		return
 	}
 
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+	comment.Version = version
+
 	if req.Content != "" {
 		comment.Content = req.Content
 	}
 
 	if err := h.commentService.Update(comment); err != nil {
+		if errors.Is(err, services.ErrVersionConflict) {
+			current, err := h.commentService.GetByID(id)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+				return
+			}
+			respondVersionConflict(c, "Comment", current, current.Version)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
 		return
 	}
 
-	c.JSON(http.StatusOK, comment)
+	respondVersioned(c, http.StatusOK, comment, comment.Version)
 }
 
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
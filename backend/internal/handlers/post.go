@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"log"
 
@@ -38,14 +42,22 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	log.Printf("DEBUG CreatePost - Category: %s", req.Category)
 	log.Printf("DEBUG CreatePost - Tags: '%s'", req.Tags)
 
-	userID, exists := c.Get("user_id")
+	actor, exists := currentActor(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
 	}
 
-	post, err := h.postService.Create(&req, userID.(uuid.UUID))
+	post, err := h.postService.Create(c.Request.Context(), &req, actor)
 	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if isPostValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
@@ -58,7 +70,13 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	status := models.PostStatus(c.DefaultQuery("status", ""))
 
-	posts, total, err := h.postService.List(limit, offset, status)
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	posts, total, err := h.postService.List(c.Request.Context(), limit, offset, status, actor)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get posts"})
 		return
@@ -72,22 +90,31 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 	})
 }
 
+// GetPublicPosts lists published posts. Optional filters: category (slug),
+// tag (slug, repeatable or comma-separated), tag_match (any|all), author
+// (username), from and to (YYYY-MM-DD or RFC 3339; date-only "to" is inclusive).
 func (h *PostHandler) GetPublicPosts(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	posts, total, err := h.postService.GetPublished(limit, offset)
+	filter, err := parsePostFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, total, err := h.postService.GetPublished(c.Request.Context(), filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get posts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respondCacheableJSON(c, gin.H{
 		"posts":  posts,
 		"total":  total,
 		"limit":  limit,
 		"offset": offset,
-	})
+	}, postsETag(strconv.FormatInt(total, 10)+":"+strconv.Itoa(limit)+":"+strconv.Itoa(offset), posts...), time.Time{}, cacheListings)
 }
 
 func (h *PostHandler) GetPost(c *gin.Context) {
@@ -97,25 +124,45 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.GetByID(id)
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	post, err := h.postService.GetForActor(c.Request.Context(), id, actor)
 	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	respondVersioned(c, http.StatusOK, post, post.Version)
 }
 
 func (h *PostHandler) GetPostBySlug(c *gin.Context) {
 	slug := c.Param("slug")
 
-	post, err := h.postService.GetBySlug(slug)
+	post, err := h.postService.GetBySlug(c.Request.Context(), slug)
 	if err != nil {
+		// The post may have been renamed; tell the client where it lives now
+		if current, moved, rerr := h.postService.ResolveSlug(c.Request.Context(), slug); rerr == nil && moved {
+			c.JSON(http.StatusOK, gin.H{
+				"redirect": true,
+				"status":   http.StatusMovedPermanently,
+				"slug":     current,
+				"location": strings.TrimSuffix(c.Request.URL.Path, slug) + current,
+			})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	respondCacheableJSON(c, post, postsETag("", post), postLastModified(post), cachePost)
 }
 
 func (h *PostHandler) UpdatePost(c *gin.Context) {
@@ -132,19 +179,43 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+	req.Version = version
+
 	// Debug logs
 	log.Printf("DEBUG UpdatePost - Received data:")
 	log.Printf("DEBUG UpdatePost - Title: %s", req.Title)
 	log.Printf("DEBUG UpdatePost - Category: %s", req.Category)
 	log.Printf("DEBUG UpdatePost - Tags: '%s'", req.Tags)
 
-	post, err := h.postService.UpdateWithAssociations(id, &req)
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	post, err := h.postService.UpdateWithAssociations(c.Request.Context(), id, &req, actor)
 	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if isPostValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrVersionConflict) {
+			h.respondPostConflict(c, id, actor)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	respondVersioned(c, http.StatusOK, post, post.Version)
 }
 
 func (h *PostHandler) DeletePost(c *gin.Context) {
@@ -154,7 +225,17 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := h.postService.Delete(id); err != nil {
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if err := h.postService.Delete(c.Request.Context(), id, actor); err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
 		return
 	}
@@ -169,7 +250,21 @@ func (h *PostHandler) PublishPost(c *gin.Context) {
 		return
 	}
 
-	if err := h.postService.Publish(id); err != nil {
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if err := h.postService.Publish(c.Request.Context(), id, actor); err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrVersionConflict) {
+			h.respondPostConflict(c, id, actor)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish post"})
 		return
 	}
@@ -184,7 +279,21 @@ func (h *PostHandler) UnpublishPost(c *gin.Context) {
 		return
 	}
 
-	if err := h.postService.Unpublish(id); err != nil {
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if err := h.postService.Unpublish(c.Request.Context(), id, actor); err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrVersionConflict) {
+			h.respondPostConflict(c, id, actor)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish post"})
 		return
 	}
@@ -192,6 +301,171 @@ func (h *PostHandler) UnpublishPost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Post unpublished successfully"})
 }
 
+func (h *PostHandler) GetRevisions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	revisions, err := h.postService.ListRevisions(c.Request.Context(), id, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"total":     len(revisions),
+	})
+}
+
+func (h *PostHandler) GetRevision(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	revision, err := h.postService.GetRevision(c.Request.Context(), id, rev, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffRevisions compares two revisions of a post. The "to" query parameter
+// defaults to the current version of the post.
+func (h *PostHandler) DiffRevisions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from revision"})
+		return
+	}
+
+	to := 0
+	if toParam := c.Query("to"); toParam != "" && toParam != "current" {
+		to, err = strconv.Atoi(toParam)
+		if err != nil || to <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to revision"})
+			return
+		}
+	}
+
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	diff, err := h.postService.DiffRevisions(c.Request.Context(), id, from, to, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (h *PostHandler) RestoreRevision(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	// The body is optional when the version comes in If-Match
+	var req struct {
+		Version int `json:"version"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	actor, exists := currentActor(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if _, err := h.postService.GetRevision(c.Request.Context(), id, rev, actor); err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	post, err := h.postService.RestoreRevision(c.Request.Context(), id, rev, version, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrVersionConflict) {
+			h.respondPostConflict(c, id, actor)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	respondVersioned(c, http.StatusOK, post, post.Version)
+}
+
 // PostPreviewRequest represents a request to preview markdown content
 type PostPreviewRequest struct {
 	Content string `json:"content" binding:"required"`
@@ -247,6 +521,84 @@ func (h *PostHandler) PreviewMarkdown(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// parsePostFilter reads the public listing filters from the query string
+func parsePostFilter(c *gin.Context) (services.PostFilter, error) {
+	filter := services.PostFilter{
+		CategorySlug:   strings.TrimSpace(c.Query("category")),
+		AuthorUsername: strings.TrimSpace(c.Query("author")),
+	}
+
+	// Repeated slugs are dropped, since tag_match=all compares the number of
+	// matching tags with the number of slugs
+	for _, value := range c.QueryArray("tag") {
+		for _, slug := range strings.Split(value, ",") {
+			if slug = strings.TrimSpace(slug); slug != "" && !slices.Contains(filter.TagSlugs, slug) {
+				filter.TagSlugs = append(filter.TagSlugs, slug)
+			}
+		}
+	}
+
+	switch c.DefaultQuery("tag_match", "any") {
+	case "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		return filter, errors.New("tag_match must be 'any' or 'all'")
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseFilterDate(from)
+		if err != nil {
+			return filter, errors.New("invalid from date")
+		}
+		filter.From = &t
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseFilterDate(to)
+		if err != nil {
+			return filter, errors.New("invalid to date")
+		}
+		if dateOnly {
+			// Include the whole day
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = &t
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+
+	return filter, nil
+}
+
+// parseFilterDate accepts a date (YYYY-MM-DD) or an RFC 3339 timestamp
+func parseFilterDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// respondPostConflict answers 409 with the post as it is now
+func (h *PostHandler) respondPostConflict(c *gin.Context, id uuid.UUID, actor services.Actor) {
+	current, err := h.postService.GetForActor(c.Request.Context(), id, actor)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	respondVersionConflict(c, "Post", current, current.Version)
+}
+
+// isPostValidationError reports whether err was caused by invalid client input
+func isPostValidationError(err error) bool {
+	return errors.Is(err, services.ErrInvalidPostStatus) ||
+		errors.Is(err, services.ErrPublishAtRequired) ||
+		errors.Is(err, services.ErrPublishAtNotAllowed)
+}
+
 // calculateReadingTimeFromWordCount estimates reading time in minutes
 func calculateReadingTimeFromWordCount(wordCount int) int {
 	const avgWordsPerMinute = 200
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Redirect Handler
+type RedirectHandler struct {
+	postService services.PostService
+	urls        services.SiteURLs
+	apiURL      string
+}
+
+func NewRedirectHandler(postService services.PostService, siteURL, apiURL string) *RedirectHandler {
+	return &RedirectHandler{
+		postService: postService,
+		urls:        services.NewSiteURLs(siteURL),
+		apiURL:      strings.TrimRight(apiURL, "/"),
+	}
+}
+
+// Post permanently redirects /blog/:slug to the canonical page of the post on
+// the blog frontend, following the slug history for renamed posts
+func (h *RedirectHandler) Post(c *gin.Context) {
+	current, _, err := h.postService.ResolveSlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	target := h.urls.Post(current)
+	// When the frontend and the API share a host the canonical page is this very
+	// route, so redirecting would loop
+	if target == h.apiURL+c.Request.URL.Path {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	c.Redirect(http.StatusMovedPermanently, target)
+}
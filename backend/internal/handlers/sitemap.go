@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Sitemap Handler
+type SitemapHandler struct {
+	sitemapService services.SitemapService
+}
+
+func NewSitemapHandler(sitemapService services.SitemapService) *SitemapHandler {
+	return &SitemapHandler{sitemapService: sitemapService}
+}
+
+// GetSitemap serves the whole sitemap when it fits in a single file and a
+// sitemap index pointing at the chunked files otherwise
+func (h *SitemapHandler) GetSitemap(c *gin.Context) {
+	pages, err := h.sitemapService.PageCount(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if pages <= 1 {
+		err = h.sitemapService.WritePage(c.Request.Context(), &buf, 1)
+	} else {
+		err = h.sitemapService.WriteIndex(&buf, pages)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+
+	respondCacheable(c, "application/xml; charset=utf-8", buf.Bytes(), time.Time{}, cacheSitemap)
+}
+
+// GetSitemapPage serves one chunk of a sitemap index, e.g. /sitemaps/2.xml
+func (h *SitemapHandler) GetSitemapPage(c *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page < 1 || !strings.HasSuffix(c.Param("page"), ".xml") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap not found"})
+		return
+	}
+
+	pages, err := h.sitemapService.PageCount(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+	if page > pages {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap not found"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.sitemapService.WritePage(c.Request.Context(), &buf, page); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sitemap"})
+		return
+	}
+
+	respondCacheable(c, "application/xml; charset=utf-8", buf.Bytes(), time.Time{}, cacheSitemap)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag is the entity tag of a versioned record, e.g. "3"
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// respondVersioned writes a versioned record with its version as the ETag, so
+// the client can send it back in If-Match when updating the record
+func respondVersioned(c *gin.Context, status int, record any, version int) {
+	c.Header("ETag", versionETag(version))
+	c.JSON(status, record)
+}
+
+// requireVersion returns the version of the record an update is based on,
+// taken from If-Match, e.g. If-Match: "3", or else from the version field of
+// the body. Without either it answers 428 Precondition Required, since a blind
+// update would overwrite changes the client has not seen. An If-Match that is
+// not a version can never match, so it fails with 412 Precondition Failed.
+func requireVersion(c *gin.Context, bodyVersion int) (int, bool) {
+	if header := strings.TrimSpace(c.GetHeader("If-Match")); header != "" {
+		version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+		if err != nil || version < 1 {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": `If-Match must hold the version of the record, e.g. "3"`})
+			return 0, false
+		}
+		return version, true
+	}
+	if bodyVersion > 0 {
+		return bodyVersion, true
+	}
+
+	c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Send the version being updated in the If-Match header or the version field"})
+	return 0, false
+}
+
+// respondVersionConflict answers an update based on a stale version with 409
+// and the record as it is now, so the client can reapply its changes to it
+func respondVersionConflict(c *gin.Context, resource string, current any, currentVersion int) {
+	c.Header("ETag", versionETag(currentVersion))
+	c.JSON(http.StatusConflict, gin.H{
+		"error":           resource + " was modified by someone else",
+		"current_version": currentVersion,
+		"current":         current,
+	})
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRequireVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifMatch     string
+		bodyVersion int
+		wantVersion int
+		wantStatus  int // 0 when the version is accepted
+	}{
+		{"strong If-Match", `"3"`, 0, 3, 0},
+		{"weak If-Match", `W/"3"`, 0, 3, 0},
+		{"unquoted If-Match", `3`, 0, 3, 0},
+		{"If-Match wins over body", `"3"`, 5, 3, 0},
+		{"body version", "", 5, 5, 0},
+		{"wildcard", `*`, 5, 0, http.StatusPreconditionFailed},
+		{"not a version", `"abc"`, 0, 0, http.StatusPreconditionFailed},
+		{"version zero", `"0"`, 0, 0, http.StatusPreconditionFailed},
+		{"no version", "", 0, 0, http.StatusPreconditionRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.ifMatch != "" {
+				c.Request.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			version, ok := requireVersion(c, tt.bodyVersion)
+			if ok != (tt.wantStatus == 0) || version != tt.wantVersion {
+				t.Errorf("requireVersion = (%d, %v), want version %d", version, ok, tt.wantVersion)
+			}
+			if tt.wantStatus != 0 && w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// fakeCategoryService keeps one category and refuses updates based on a
+// version other than the stored one, like the versioned repository update
+type fakeCategoryService struct {
+	services.CategoryService
+	stored  *models.Category
+	deleted bool // the category disappears when an update conflicts
+}
+
+func (s *fakeCategoryService) GetByID(id uuid.UUID) (*models.Category, error) {
+	if s.stored == nil || s.stored.ID != id {
+		return nil, errors.New("record not found")
+	}
+	copied := *s.stored
+	return &copied, nil
+}
+
+func (s *fakeCategoryService) Update(category *models.Category) error {
+	if category.Version != s.stored.Version {
+		if s.deleted {
+			s.stored = nil
+		}
+		return services.ErrVersionConflict
+	}
+	category.Version++
+	copied := *category
+	s.stored = &copied
+	return nil
+}
+
+func TestUpdateCategoryVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifMatch     string
+		body        string
+		deleted     bool
+		wantStatus  int
+		wantETag    string
+		wantVersion float64 // version or current_version in the response
+	}{
+		{"current version", `"4"`, `{"name":"Golang"}`, false, http.StatusOK, `"5"`, 5},
+		{"current version in body", "", `{"name":"Golang","version":4}`, false, http.StatusOK, `"5"`, 5},
+		{"stale version", `"3"`, `{"name":"Golang"}`, false, http.StatusConflict, `"4"`, 4},
+		{"deleted meanwhile", `"3"`, `{"name":"Golang"}`, true, http.StatusNotFound, "", 0},
+		{"no version", "", `{"name":"Golang"}`, false, http.StatusPreconditionRequired, "", 0},
+		{"malformed If-Match", `"four"`, `{"name":"Golang"}`, false, http.StatusPreconditionFailed, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := uuid.New()
+			service := &fakeCategoryService{
+				stored:  &models.Category{ID: id, Name: "Go", Slug: "go", Version: 4},
+				deleted: tt.deleted,
+			}
+			router := gin.New()
+			router.PUT("/categories/:id", NewCategoryHandler(service).UpdateCategory)
+
+			req := httptest.NewRequest(http.MethodPut, "/categories/"+id.String(), strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+
+			var resp map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			switch tt.wantStatus {
+			case http.StatusOK:
+				if resp["version"] != tt.wantVersion || resp["name"] != "Golang" {
+					t.Errorf("response = %v, want the updated category at version %v", resp, tt.wantVersion)
+				}
+			case http.StatusConflict:
+				current, _ := resp["current"].(map[string]any)
+				if resp["current_version"] != tt.wantVersion || current["name"] != "Go" {
+					t.Errorf("response = %v, want the stored category at version %v", resp, tt.wantVersion)
+				}
+				if service.stored.Name != "Go" {
+					t.Error("conflicting update was saved")
+				}
+			}
+		})
+	}
+}
+
+// fakePostService restores revisions of a single stored post
+type fakePostService struct {
+	services.PostService
+	stored *models.Post
+}
+
+func (s *fakePostService) GetForActor(ctx context.Context, id uuid.UUID, actor services.Actor) (*models.Post, error) {
+	copied := *s.stored
+	return &copied, nil
+}
+
+func (s *fakePostService) GetRevision(ctx context.Context, postID uuid.UUID, revision int, actor services.Actor) (*models.PostRevision, error) {
+	return &models.PostRevision{PostID: postID, Revision: revision, Title: "Old title"}, nil
+}
+
+func (s *fakePostService) RestoreRevision(ctx context.Context, postID uuid.UUID, revision, version int, actor services.Actor) (*models.Post, error) {
+	if version != s.stored.Version {
+		return nil, services.ErrVersionConflict
+	}
+	s.stored.Title = "Old title"
+	s.stored.Version++
+	copied := *s.stored
+	return &copied, nil
+}
+
+func TestRestoreRevisionVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		body       string
+		wantStatus int
+		wantETag   string
+	}{
+		{"current version", `"4"`, "", http.StatusOK, `"5"`},
+		{"current version in body", "", `{"version":4}`, http.StatusOK, `"5"`},
+		{"stale version", `"3"`, "", http.StatusConflict, `"4"`},
+		{"no version", "", "", http.StatusPreconditionRequired, ""},
+		{"malformed body", "", `{"version":`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := uuid.New()
+			service := &fakePostService{stored: &models.Post{ID: id, Title: "New title", Version: 4}}
+			router := gin.New()
+			router.POST("/posts/:id/revisions/:rev/restore", func(c *gin.Context) {
+				c.Set("user_id", uuid.New())
+				c.Set("user_role", models.RoleAdmin)
+			}, NewPostHandler(service, nil).RestoreRevision)
+
+			req := httptest.NewRequest(http.MethodPost, "/posts/"+id.String()+"/revisions/2/restore", strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			if wantTitle := "New title"; tt.wantStatus == http.StatusOK {
+				if service.stored.Title == wantTitle {
+					t.Error("revision was not restored")
+				}
+			} else if service.stored.Title != wantTitle {
+				t.Error("revision was restored without a current version")
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"os"
 	"strings"
 
@@ -10,12 +11,15 @@ import (
 // Global logger instance
 var Logger *lazylog.Logger
 
+// transports of the global logger, kept so Close can flush them
+var transports []lazylog.Transport
+
 // Initialize initializes the global logger with the provided configuration
 func Initialize(logLevel, logFormat, logOutput string) error {
 	level := parseLogLevel(logLevel)
 	formatter := parseLogFormatter(logFormat)
 
-	var transports []lazylog.Transport
+	transports = nil
 
 	// Create logs directory if it doesn't exist
 	if err := os.MkdirAll("logs", 0755); err != nil {
@@ -83,6 +87,16 @@ func Initialize(logLevel, logFormat, logOutput string) error {
 	return nil
 }
 
+// Close flushes and closes the transports that write to files. Nothing should
+// be logged afterwards.
+func Close() {
+	for _, transport := range transports {
+		if closer, ok := transport.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
 // parseLogLevel converts string level to lazylog level
 func parseLogLevel(level string) lazylog.Level {
 	switch strings.ToUpper(level) {
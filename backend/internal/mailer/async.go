@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/chmenegatti/myBlog/internal/logger"
+)
+
+// ErrClosed is returned by Async.Send once shutdown has started
+var ErrClosed = errors.New("mailer is shutting down")
+
+// Async delivers messages in the background, so slow mail servers do not hold
+// up responses, and lets shutdown wait for the messages still in flight
+type Async struct {
+	mailer Mailer
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewAsync(mailer Mailer) *Async {
+	return &Async{mailer: mailer}
+}
+
+// Send queues the message and returns immediately. Delivery failures are
+// logged.
+func (a *Async) Send(msg Message) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return ErrClosed
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := a.mailer.Send(msg); err != nil {
+			logger.WithError(err).Error("Failed to send email", map[string]any{
+				"subject": msg.Subject,
+			})
+		}
+	}()
+	return nil
+}
+
+// Close stops accepting messages and waits until the queued ones are sent or
+// ctx is done
+func (a *Async) Close(ctx context.Context) error {
+	a.mu.Lock()
+	a.closed = true
+	a.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/logger"
+)
+
+// fileMailer writes each message as an .eml file, for local development
+type fileMailer struct {
+	dir  string
+	from *mail.Address
+}
+
+func newFileMailer(dir string, from *mail.Address) (*fileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &fileMailer{dir: dir, from: from}, nil
+}
+
+func (m *fileMailer) Send(msg Message) error {
+	data, err := compose(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), messageID()[:8])
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+
+	logger.Info("Email written to file", map[string]any{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"path":    path,
+	})
+	return nil
+}
+
+// logMailer only logs messages, body included, so links can be copied from the
+// logs during development. The API warns when it is used in production.
+type logMailer struct {
+	from *mail.Address
+}
+
+func newLogMailer(from *mail.Address) *logMailer {
+	return &logMailer{from: from}
+}
+
+func (m *logMailer) Send(msg Message) error {
+	if _, err := compose(m.from, msg); err != nil {
+		return err
+	}
+
+	logger.Info("Email not sent (log mail driver)", map[string]any{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	return nil
+}
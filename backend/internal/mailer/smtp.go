@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+)
+
+// smtpMailer delivers messages through an SMTP relay. Port 465 uses implicit
+// TLS; any other port upgrades with STARTTLS when the server offers it.
+type smtpMailer struct {
+	addr     string
+	host     string
+	port     int
+	username string
+	password string
+	from     *mail.Address
+}
+
+func newSMTPMailer(cfg config.MailConfig, from *mail.Address) *smtpMailer {
+	return &smtpMailer{
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:     cfg.Host,
+		port:     cfg.Port,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     from,
+	}
+}
+
+func (m *smtpMailer) Send(msg Message) error {
+	data, err := compose(m.from, msg)
+	if err != nil {
+		return err
+	}
+	to, _ := mail.ParseAddress(msg.To)
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	if m.port != 465 {
+		return smtp.SendMail(m.addr, auth, m.from.Address, []string{to.Address}, data)
+	}
+
+	conn, err := tls.Dial("tcp", m.addr, &tls.Config{ServerName: m.host})
+	if err != nil {
+		return fmt.Errorf("smtp connect: %w", err)
+	}
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
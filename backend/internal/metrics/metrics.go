@@ -0,0 +1,94 @@
+// Package metrics defines the Prometheus metrics of the API. The metrics are
+// shared by the whole process; NewRegistry gathers them for /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HTTP and database metrics
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of GORM operations by operation and table.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "table"})
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Failed GORM operations by operation and table, not counting record not found.",
+	}, []string{"operation", "table"})
+	HTTPRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Requests rejected with 429 by a rate limit, by route group.",
+	}, []string{"limit"})
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Lookups in the response cache by area and result (hit or miss).",
+	}, []string{"area", "result"})
+)
+
+// Business metrics
+var (
+	PostsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "myblog_posts_published_total",
+		Help: "Posts moved to published, by trigger (manual or scheduled).",
+	}, []string{"trigger"})
+	Uploads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "myblog_uploads_total",
+		Help: "Uploaded images by category.",
+	}, []string{"category"})
+	UploadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "myblog_upload_bytes_total",
+		Help: "Bytes of uploaded images by category.",
+	}, []string{"category"})
+	NewsletterSubscriptions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "myblog_newsletter_subscriptions_total",
+		Help: "Newsletter subscriptions, reactivations included.",
+	})
+)
+
+// shared lists the package metrics, which every registry serves
+var shared = []prometheus.Collector{
+	HTTPRequests, HTTPRequestDuration, DBQueryDuration, DBQueryErrors, HTTPRateLimited, CacheRequests,
+	PostsPublished, Uploads, UploadBytes, NewsletterSubscriptions,
+}
+
+// NewRegistry returns a registry serving the package metrics, the Go and
+// process collectors and the connection pool statistics of db as the go_sql_*
+// metrics. Each App builds its own, so several can live in one process.
+func NewRegistry(db *sql.DB) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(shared...)
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "myblog"),
+	)
+	return registry
+}
+
+// RegisterGaugeFunc exposes a gauge in registry, read when scraped. A failing
+// read reports NaN, so that a broken gauge is not mistaken for zero.
+func RegisterGaugeFunc(registry prometheus.Registerer, name, help string, read func() (float64, error)) {
+	promauto.With(registry).NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, func() float64 {
+		value, err := read()
+		if err != nil {
+			return math.NaN()
+		}
+		return value
+	})
+}
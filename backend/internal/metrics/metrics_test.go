@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewRegistry(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Every App builds its own registry; a second one must not panic on the
+	// shared metrics
+	first := NewRegistry(db)
+	second := NewRegistry(db)
+	RegisterGaugeFunc(first, "myblog_test_gauge", "Test gauge.", func() (float64, error) { return 3, nil })
+	RegisterGaugeFunc(second, "myblog_test_gauge", "Test gauge.", func() (float64, error) { return 0, errors.New("down") })
+	HTTPRequests.WithLabelValues("GET", "/health", "200").Inc()
+
+	tests := []struct {
+		name     string
+		registry *prometheus.Registry
+		gauge    float64
+	}{
+		{"first", first, 3},
+		{"second", second, math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			families, err := tt.registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := map[string]bool{}
+			gauge := 0.0
+			for _, family := range families {
+				found[family.GetName()] = true
+				if family.GetName() == "myblog_test_gauge" {
+					gauge = family.Metric[0].GetGauge().GetValue()
+				}
+			}
+			for _, name := range []string{"http_requests_total", "go_goroutines", "go_sql_open_connections", "myblog_test_gauge"} {
+				if !found[name] {
+					t.Errorf("registry is missing %s", name)
+				}
+			}
+			if gauge != tt.gauge && !(math.IsNaN(gauge) && math.IsNaN(tt.gauge)) {
+				t.Errorf("gauge = %v, want %v", gauge, tt.gauge)
+			}
+		})
+	}
+}
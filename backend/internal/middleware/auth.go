@@ -1,16 +1,19 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/chmenegatti/myBlog/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthRequired middleware for protected routes
-func AuthRequired(jwtConfig config.JWTConfig) gin.HandlerFunc {
+// AuthRequired middleware for protected routes. Besides JWTs it accepts
+// personal API tokens, in which case the token is stored in the context under
+// "api_token" so RequirePermission and RequireScope can check its scopes.
+func AuthRequired(keys *services.KeySet, apiTokens services.APITokenService) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -28,7 +31,23 @@ func AuthRequired(jwtConfig config.JWTConfig) gin.HandlerFunc {
 		}
 
 		token := parts[1]
-		claims, err := services.ValidateJWT(token, jwtConfig.Secret)
+		if strings.HasPrefix(token, services.APITokenPrefix) {
+			apiToken, user, err := apiTokens.Authenticate(token)
+			if err != nil {
+				LogSecurityEvent("invalid_api_token", c.Request.Method+" "+c.FullPath(), "", err.Error(), "low")
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", user.ID)
+			c.Set("user_role", user.Role)
+			c.Set("api_token", apiToken)
+			c.Next()
+			return
+		}
+
+		claims, err := services.ValidateJWT(token, keys)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
@@ -42,11 +61,51 @@ func AuthRequired(jwtConfig config.JWTConfig) gin.HandlerFunc {
 	})
 }
 
+// APIToken returns the personal API token the request was authenticated
+// with, or nil for a login session
+func APIToken(c *gin.Context) *models.APIToken {
+	token, _ := c.Get("api_token")
+	apiToken, _ := token.(*models.APIToken)
+	return apiToken
+}
+
+// TwoFactorEnrollment middleware authenticates like AuthRequired but also
+// accepts the challenge token returned by a login that requires the user to
+// enroll in two-factor authentication first. "two_factor_enrollment" is set in
+// the context when the request used such a challenge.
+func TwoFactorEnrollment(keys *services.KeySet) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.Abort()
+			return
+		}
+
+		enrolling := false
+		claims, err := services.ValidateJWT(parts[1], keys)
+		if err != nil {
+			claims, err = services.ValidateEnrollmentJWT(parts[1], keys)
+			enrolling = true
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("user_role", claims.Role)
+		c.Set("two_factor_enrollment", enrolling)
+		c.Next()
+	})
+}
+
 // AdminRequired middleware for admin-only routes
 func AdminRequired() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		userRole, exists := c.Get("user_role")
-		if !exists || userRole != "admin" {
+		if !exists || userRole != models.RoleAdmin {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
@@ -54,3 +113,49 @@ func AdminRequired() gin.HandlerFunc {
 		c.Next()
 	})
 }
+
+// RequirePermission middleware only lets through users whose role is granted
+// the permission. It must run after AuthRequired.
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		role, _ := c.Get("user_role")
+		userRole, _ := role.(models.UserRole)
+		apiToken := APIToken(c)
+		if !userRole.Can(permission) || (apiToken != nil && !apiToken.Allows(permission)) {
+			userID, _ := c.Get("user_id")
+			LogSecurityEvent("permission_denied", c.Request.Method+" "+c.FullPath(), fmt.Sprint(userID), string(permission), "low")
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+}
+
+// RequireScope middleware restricts personal API tokens to those carrying one
+// of the scopes. Login sessions are let through. It must run after
+// AuthRequired.
+func RequireScope(scopes ...models.APITokenScope) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if apiToken := APIToken(c); apiToken != nil && !apiToken.HasScope(scopes...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API token is missing the required scope"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+}
+
+// SessionRequired middleware refuses personal API tokens on routes that manage
+// the account itself, such as credentials and other tokens. It must run after
+// AuthRequired.
+func SessionRequired() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if APIToken(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint is not available to API tokens"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+}
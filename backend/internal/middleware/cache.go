@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// immutableCacheControl lets browsers and CDNs keep a response for a year
+// without revalidating it
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// Immutable marks successful responses as never changing, for files that get
+// a new name whenever their content changes. Errors are left uncached, so a
+// file missing now can still be served once it exists.
+func Immutable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &immutableWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// immutableWriter adds Cache-Control once the status of the response is known
+type immutableWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *immutableWriter) WriteHeader(code int) {
+	if code < 400 && !w.Written() {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *immutableWriter) Write(data []byte) (int, error) {
+	if !w.Written() {
+		w.WriteHeader(w.Status())
+	}
+	return w.ResponseWriter.Write(data)
+}
@@ -23,6 +23,9 @@ func CORS(cfg config.CORSConfig) gin.HandlerFunc {
 			}
 		}
 
+		// The allowed origin is echoed back, so shared caches must keep a copy per origin
+		c.Writer.Header().Add("Vary", "Origin")
+
 		// Set CORS headers
 		if allowed {
 			c.Header("Access-Control-Allow-Origin", origin)
@@ -35,7 +38,7 @@ func CORS(cfg config.CORSConfig) gin.HandlerFunc {
 
 		c.Header("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
 		c.Header("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, ETag, Last-Modified, Retry-After, RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Max-Age", "86400")
 
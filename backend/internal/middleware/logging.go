@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -15,6 +16,10 @@ func RequestLogger() gin.HandlerFunc {
 		requestID := uuid.New().String()
 		c.Set("request_id", requestID)
 
+		// Correlate the logs of this request with its trace
+		traceID, spanID := tracing.IDs(c.Request.Context())
+		c.Set("trace_id", traceID)
+
 		// Start timer
 		start := time.Now()
 
@@ -50,6 +55,8 @@ func RequestLogger() gin.HandlerFunc {
 		// Create log entry with structured fields
 		fields := map[string]any{
 			"request_id": requestID,
+			"trace_id":   traceID,
+			"span_id":    spanID,
 			"user_id":    userID,
 		}
 
@@ -73,10 +80,12 @@ func ErrorLogger() gin.HandlerFunc {
 		// Log any errors that occurred during request processing
 		for _, err := range c.Errors {
 			requestID, _ := c.Get("request_id")
+			traceID, _ := c.Get("trace_id")
 			userID, _ := c.Get("user_id")
 
 			logger.WithFields(map[string]any{
 				"request_id": requestID,
+				"trace_id":   traceID,
 				"user_id":    userID,
 				"method":     c.Request.Method,
 				"path":       c.Request.URL.Path,
@@ -91,11 +100,13 @@ func ErrorLogger() gin.HandlerFunc {
 func Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
 		requestID, _ := c.Get("request_id")
+		traceID, _ := c.Get("trace_id")
 		userID, _ := c.Get("user_id")
 
 		// Log panic with stack trace
 		fields := map[string]any{
 			"request_id": requestID,
+			"trace_id":   traceID,
 			"user_id":    userID,
 			"method":     c.Request.Method,
 			"path":       c.Request.URL.Path,
@@ -118,10 +129,12 @@ func PerformanceLogger(threshold time.Duration) gin.HandlerFunc {
 		// Log slow requests
 		if duration > threshold {
 			requestID, _ := c.Get("request_id")
+			traceID, _ := c.Get("trace_id")
 			userID, _ := c.Get("user_id")
 
 			fields := map[string]any{
 				"request_id": requestID,
+				"trace_id":   traceID,
 				"user_id":    userID,
 				"method":     c.Request.Method,
 				"path":       c.Request.URL.Path,
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/chmenegatti/myBlog/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace of a
+// W3C traceparent header, and stores it in the request context so services
+// and queries given c.Request.Context() are recorded as its children. It must
+// run before RequestLogger for trace IDs to appear in the request logs.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = "_OTHER"
+		}
+		route := c.FullPath()
+		name := method + " " + route
+		if route == "" {
+			name = method
+		}
+
+		ctx, span := tracing.StartRequest(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header), name,
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+		if route != "" {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
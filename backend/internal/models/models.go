@@ -4,20 +4,38 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
 // User represents a blog user (admin/author)
 type User struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Username  string         `json:"username" gorm:"unique;not null"`
-	Email     string         `json:"email" gorm:"unique;not null"`
-	Password  string         `json:"-" gorm:"not null"`
-	Name      string         `json:"name" gorm:"not null"`
-	Bio       string         `json:"bio"`
-	Avatar    string         `json:"avatar"`
-	Role      UserRole       `json:"role" gorm:"default:'author'"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Username string    `json:"username" gorm:"unique;not null"`
+	Email    string    `json:"email" gorm:"unique;not null"`
+	Password string    `json:"-" gorm:"not null"`
+	Name     string    `json:"name" gorm:"not null"`
+	Bio      string    `json:"bio"`
+	Avatar   string    `json:"avatar"`
+	Role     UserRole  `json:"role" gorm:"default:'author'"`
+	IsActive bool      `json:"is_active"` // no GORM default, so that false is inserted as given
+
+	// Set once the user proves they own Email. Self-registered accounts stay
+	// inactive until then.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// TOTP two-factor authentication. TOTPSecret is set on setup and only
+	// used for login once TOTPEnabled is confirmed.
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"default:false"`
+	TOTPLastStep int64  `json:"-"` // last accepted time step, so a code cannot be replayed
+
+	// Brute-force protection. Consecutive failed logins lock the account
+	// until LockedUntil.
+	FailedLoginAttempts int        `json:"-" gorm:"default:0"`
+	LastFailedLoginAt   *time.Time `json:"-"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -29,10 +47,199 @@ type User struct {
 type UserRole string
 
 const (
-	RoleAdmin  UserRole = "admin"
-	RoleAuthor UserRole = "author"
+	RoleAdmin       UserRole = "admin"
+	RoleEditor      UserRole = "editor"
+	RoleAuthor      UserRole = "author"
+	RoleContributor UserRole = "contributor"
+)
+
+// Permission is an action a role may be granted
+type Permission string
+
+const (
+	PermCreatePosts      Permission = "posts:create"      // write drafts of one's own
+	PermPublishPosts     Permission = "posts:publish"     // publish and schedule one's own posts
+	PermManagePosts      Permission = "posts:manage"      // edit, publish and delete anyone's posts
+	PermModerateComments Permission = "comments:moderate" // list, edit, approve and delete comments
+	PermManageTaxonomy   Permission = "taxonomy:manage"   // create, edit and delete categories and tags
+	PermUploadImages     Permission = "images:upload"     // upload and delete one's own images
+	PermManageImages     Permission = "images:manage"     // see and delete anyone's images
+	PermManageNewsletter Permission = "newsletter:manage" // see and remove subscribers
+	PermManageUsers      Permission = "users:manage"      // create, edit, deactivate and delete users
+)
+
+// rolePermissions is the permission matrix. Each role extends the one below it.
+var rolePermissions = map[UserRole][]Permission{
+	RoleContributor: {PermCreatePosts, PermUploadImages},
+	RoleAuthor:      {PermCreatePosts, PermUploadImages, PermPublishPosts},
+	RoleEditor: {PermCreatePosts, PermUploadImages, PermPublishPosts,
+		PermManagePosts, PermModerateComments, PermManageTaxonomy, PermManageImages},
+	RoleAdmin: {PermCreatePosts, PermUploadImages, PermPublishPosts,
+		PermManagePosts, PermModerateComments, PermManageTaxonomy, PermManageImages,
+		PermManageNewsletter, PermManageUsers},
+}
+
+// Valid reports whether r is a known role
+func (r UserRole) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role is granted the permission
+func (r UserRole) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// APITokenScope limits what a personal API token may do on behalf of its
+// owner. A token never grants more than the owner's role allows.
+type APITokenScope string
+
+const (
+	ScopePostsRead       APITokenScope = "posts:read"
+	ScopePostsWrite      APITokenScope = "posts:write"
+	ScopeImagesWrite     APITokenScope = "images:write"
+	ScopeTaxonomyWrite   APITokenScope = "taxonomy:write"
+	ScopeCommentsWrite   APITokenScope = "comments:write"
+	ScopeNewsletterWrite APITokenScope = "newsletter:write"
 )
 
+// scopePermissions lists the role permissions each scope unlocks. No scope
+// unlocks user management.
+var scopePermissions = map[APITokenScope][]Permission{
+	ScopePostsRead:       {},
+	ScopePostsWrite:      {PermCreatePosts, PermPublishPosts, PermManagePosts},
+	ScopeImagesWrite:     {PermUploadImages, PermManageImages},
+	ScopeTaxonomyWrite:   {PermManageTaxonomy},
+	ScopeCommentsWrite:   {PermModerateComments},
+	ScopeNewsletterWrite: {PermManageNewsletter},
+}
+
+// Valid reports whether s is a known scope
+func (s APITokenScope) Valid() bool {
+	_, ok := scopePermissions[s]
+	return ok
+}
+
+// Permissions lists the role permissions the scope unlocks
+func (s APITokenScope) Permissions() []Permission {
+	return scopePermissions[s]
+}
+
+// Allows reports whether the scope unlocks the permission
+func (s APITokenScope) Allows(p Permission) bool {
+	for _, granted := range scopePermissions[s] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a named personal access token for scripts and CI. Only the
+// SHA-256 hash of the token is stored; Prefix keeps its first characters so
+// users can tell their tokens apart.
+type APIToken struct {
+	ID         uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name       string         `json:"name" gorm:"not null"`
+	Prefix     string         `json:"prefix" gorm:"not null"`
+	TokenHash  string         `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     pq.StringArray `json:"scopes" gorm:"type:text[]"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Allows reports whether any of the token's scopes unlocks the permission
+func (t *APIToken) Allows(p Permission) bool {
+	for _, scope := range t.Scopes {
+		if APITokenScope(scope).Allows(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the token carries any of the scopes
+func (t *APIToken) HasScope(scopes ...APITokenScope) bool {
+	for _, have := range t.Scopes {
+		for _, want := range scopes {
+			if APITokenScope(have) == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RefreshToken is a long-lived login session credential. Only the SHA-256 hash
+// of the opaque token is stored. Every refresh rotates the token; all tokens
+// descending from the same login share a FamilyID so that reuse of a rotated
+// token can revoke the whole session.
+type RefreshToken struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	FamilyID     uuid.UUID  `json:"family_id" gorm:"type:uuid;not null;index"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uuid.UUID `json:"replaced_by_id" gorm:"type:uuid"`
+	UserAgent    string     `json:"user_agent"`
+	IPAddress    string     `json:"ip_address"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Codes are stored as bcrypt hashes.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// EmailVerificationToken is emailed after registration to confirm the
+// address. Only the SHA-256 hash of the token is stored.
+type EmailVerificationToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Invitation lets someone register with a preassigned role. When Email is
+// set, only that address may use it. Only the SHA-256 hash of the token is
+// stored.
+type Invitation struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Email       string     `json:"email"`
+	Role        UserRole   `json:"role" gorm:"not null"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedByID uuid.UUID  `json:"invited_by_id" gorm:"type:uuid;not null"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt      *time.Time `json:"used_at"`
+	UsedByID    *uuid.UUID `json:"used_by_id" gorm:"type:uuid"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// PasswordResetToken is a single-use credential emailed to reset a password.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 // Post represents a blog post
 type Post struct {
 	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -48,6 +255,8 @@ type Post struct {
 	ReadingTime int            `json:"reading_time" gorm:"default:0"` // Estimated reading time in minutes
 	WordCount   int            `json:"word_count" gorm:"default:0"`   // Word count of content
 	PublishedAt *time.Time     `json:"published_at"`
+	PublishAt   *time.Time     `json:"publish_at" gorm:"index"`           // Scheduled publication time
+	Version     int            `json:"version" gorm:"not null;default:1"` // Bumped by every update, for optimistic locking
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -63,10 +272,46 @@ type PostStatus string
 
 const (
 	StatusDraft     PostStatus = "draft"
+	StatusScheduled PostStatus = "scheduled"
 	StatusPublished PostStatus = "published"
 	StatusArchived  PostStatus = "archived"
 )
 
+// PostRevision is a snapshot of a post taken before it is overwritten by an update
+type PostRevision struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PostID      uuid.UUID      `json:"post_id" gorm:"type:uuid;not null;uniqueIndex:idx_post_revisions_post_revision"`
+	Revision    int            `json:"revision" gorm:"not null;uniqueIndex:idx_post_revisions_post_revision"`
+	Title       string         `json:"title"`
+	Slug        string         `json:"slug"`
+	Excerpt     string         `json:"excerpt"`
+	Content     string         `json:"content" gorm:"type:text"`
+	FeaturedImg string         `json:"featured_img"`
+	Status      PostStatus     `json:"status"`
+	CategoryIDs pq.StringArray `json:"category_ids" gorm:"type:text[]"`
+	TagIDs      pq.StringArray `json:"tag_ids" gorm:"type:text[]"`
+	EditorID    *uuid.UUID     `json:"editor_id" gorm:"type:uuid"` // User whose update replaced this revision
+	CreatedAt   time.Time      `json:"created_at"`
+
+	// Relationships
+	Editor *User `json:"editor,omitempty" gorm:"foreignKey:EditorID"`
+}
+
+// PostSlugHistory records a slug a post used to have, so old links can be redirected
+type PostSlugHistory struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PostID    uuid.UUID `json:"post_id" gorm:"type:uuid;not null;index"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PostSearchResult is a full-text search hit. It is not persisted.
+type PostSearchResult struct {
+	Post    *Post   `json:"post"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"` // HTML-escaped excerpt with matches wrapped in <mark>
+}
+
 // Category represents a blog category
 type Category struct {
 	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -74,6 +319,7 @@ type Category struct {
 	Slug        string         `json:"slug" gorm:"unique;not null"`
 	Description string         `json:"description"`
 	Color       string         `json:"color" gorm:"default:'#6B7280'"` // Tailwind gray-500
+	Version     int            `json:"version" gorm:"not null;default:1"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -87,6 +333,7 @@ type Tag struct {
 	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	Name      string         `json:"name" gorm:"unique;not null"`
 	Slug      string         `json:"slug" gorm:"unique;not null"`
+	Version   int            `json:"version" gorm:"not null;default:1"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -105,6 +352,7 @@ type Comment struct {
 	Website   string         `json:"website"`
 	Content   string         `json:"content" gorm:"type:text;not null"`
 	Status    CommentStatus  `json:"status" gorm:"default:'pending'"`
+	Version   int            `json:"version" gorm:"not null;default:1"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -0,0 +1,107 @@
+package models
+
+import "testing"
+
+func TestUserRoleValid(t *testing.T) {
+	tests := []struct {
+		role UserRole
+		want bool
+	}{
+		{RoleAdmin, true},
+		{RoleEditor, true},
+		{RoleAuthor, true},
+		{RoleContributor, true},
+		{"", false},
+		{"superuser", false},
+		{"Admin", false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Valid(); got != tt.want {
+			t.Errorf("UserRole(%q).Valid() = %v, want %v", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestUserRoleCan(t *testing.T) {
+	perms := []Permission{
+		PermCreatePosts, PermUploadImages, PermPublishPosts,
+		PermManagePosts, PermModerateComments, PermManageTaxonomy, PermManageImages,
+		PermManageNewsletter, PermManageUsers,
+	}
+	tests := []struct {
+		role    UserRole
+		granted []Permission
+	}{
+		{RoleContributor, []Permission{PermCreatePosts, PermUploadImages}},
+		{RoleAuthor, []Permission{PermCreatePosts, PermUploadImages, PermPublishPosts}},
+		{RoleEditor, []Permission{PermCreatePosts, PermUploadImages, PermPublishPosts,
+			PermManagePosts, PermModerateComments, PermManageTaxonomy, PermManageImages}},
+		{RoleAdmin, perms},
+		{"unknown", nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			for _, p := range perms {
+				want := false
+				for _, g := range tt.granted {
+					if g == p {
+						want = true
+					}
+				}
+				if got := tt.role.Can(p); got != want {
+					t.Errorf("Can(%q) = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAPITokenAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		perm   Permission
+		want   bool
+	}{
+		{"no scopes", nil, PermCreatePosts, false},
+		{"read only", []string{"posts:read"}, PermCreatePosts, false},
+		{"posts write", []string{"posts:write"}, PermManagePosts, true},
+		{"images write", []string{"images:write"}, PermManageImages, true},
+		{"wrong scope", []string{"images:write"}, PermPublishPosts, false},
+		{"second scope", []string{"posts:read", "taxonomy:write"}, PermManageTaxonomy, true},
+		{"unknown scope", []string{"users:manage"}, PermManageUsers, false},
+		{"user management is never allowed", []string{"posts:write", "images:write", "taxonomy:write",
+			"comments:write", "newsletter:write"}, PermManageUsers, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &APIToken{Scopes: tt.scopes}
+			if got := token.Allows(tt.perm); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPITokenHasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		want   []APITokenScope
+		has    bool
+	}{
+		{"match", []string{"posts:read"}, []APITokenScope{ScopePostsRead}, true},
+		{"any of", []string{"posts:write"}, []APITokenScope{ScopePostsRead, ScopePostsWrite}, true},
+		{"missing", []string{"images:write"}, []APITokenScope{ScopePostsRead}, false},
+		{"no scopes", nil, []APITokenScope{ScopePostsRead}, false},
+		{"nothing asked", []string{"posts:read"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &APIToken{Scopes: tt.scopes}
+			if got := token.HasScope(tt.want...); got != tt.has {
+				t.Errorf("HasScope(%v) = %v, want %v", tt.want, got, tt.has)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+
+	runBucketSteps(t, store, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+	slow := Limit{Requests: 1, Period: time.Hour}
+
+	for _, key := range []string{"refilled", "empty"} {
+		if _, err := store.Take(context.Background(), key, bucketLimit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Take(context.Background(), "empty", slow); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(sweepInterval)
+	if _, err := store.Take(context.Background(), "trigger", bucketLimit); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.buckets["refilled"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+	if _, ok := store.buckets["empty"]; !ok {
+		t.Error("bucket still refilling was swept")
+	}
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+)
+
+// Route groups with their own limit
+const (
+	GroupLogin        = "login"
+	GroupRegister     = "register"
+	GroupAccountEmail = "account_email" // password resets and verification emails
+	GroupComments     = "comments"
+	GroupNewsletter   = "newsletter"
+	GroupAPI          = "api"
+)
+
+// Limit is a token bucket holding up to Requests tokens that refills
+// completely over Period, so bursts of Requests are allowed but the sustained
+// rate is Requests per Period. The zero Limit allows everything.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses limits written as "<requests>/<period>", e.g. "10/1m" or
+// "100/h". An empty string, "0" or "off" is the zero Limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" || strings.EqualFold(s, "off") {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/period", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid request count in rate limit %q", s)
+	}
+	period = strings.TrimSpace(period)
+	if period != "" && !strings.ContainsAny(period[:1], "0123456789") {
+		period = "1" + period // "h" is short for "1h"
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid period in rate limit %q", s)
+	}
+	if n == 0 {
+		return Limit{}, nil
+	}
+	if d/time.Duration(n) < time.Microsecond {
+		return Limit{}, fmt.Errorf("rate limit %q is too high", s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Enabled reports whether the limit restricts anything
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Policy renders the limit for the RateLimit-Policy header
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Period.Seconds())))
+}
+
+// interval is the time it takes to refill a single token
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// result describes a bucket holding tokens after a request was allowed or denied
+func (l Limit) result(allowed bool, tokens float64) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     l.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(l.Requests) - tokens) * float64(l.interval())),
+	}
+	if !allowed {
+		r.RetryAfter = time.Duration((1 - tokens) * float64(l.interval()))
+	}
+	return r
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, when this one was not
+}
+
+// Store keeps the token buckets
+type Store interface {
+	// Take removes a token from the bucket of key, which starts full
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	Close() error
+}
+
+// Limiter applies the configured limit of each route group, with buckets kept
+// in a store shared by every group
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// New creates a limiter with the store selected by cfg.Store. When rate
+// limiting is disabled every group is unlimited.
+func New(cfg config.RateLimitConfig) (*Limiter, error) {
+	limiter := &Limiter{limits: make(map[string]Limit)}
+	if !cfg.Enabled {
+		return limiter, nil
+	}
+
+	for group, value := range map[string]string{
+		GroupLogin:        cfg.Login,
+		GroupRegister:     cfg.Register,
+		GroupAccountEmail: cfg.AccountEmail,
+		GroupComments:     cfg.Comments,
+		GroupNewsletter:   cfg.Newsletter,
+		GroupAPI:          cfg.API,
+	} {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("RATE_LIMIT_%s: %w", strings.ToUpper(group), err)
+		}
+		limiter.limits[group] = limit
+	}
+
+	switch cfg.Store {
+	case "memory", "":
+		limiter.store = NewMemoryStore()
+	case "redis":
+		store, err := NewRedisStore(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		limiter.store = store
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+	return limiter, nil
+}
+
+// Limit returns the limit of group
+func (l *Limiter) Limit(group string) Limit {
+	return l.limits[group]
+}
+
+// Take counts a request of key against the limit of group
+func (l *Limiter) Take(ctx context.Context, group, key string) (Result, error) {
+	limit := l.limits[group]
+	if !limit.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+	return l.store.Take(ctx, group+":"+key, limit)
+}
+
+// Close releases the store
+func (l *Limiter) Close() error {
+	if l.store == nil {
+		return nil
+	}
+	return l.store.Close()
+}
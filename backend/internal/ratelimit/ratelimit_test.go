@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"10/1m", Limit{10, time.Minute}, false},
+		{" 100 / h ", Limit{100, time.Hour}, false},
+		{"5/30s", Limit{5, 30 * time.Second}, false},
+		{"", Limit{}, false},
+		{"0", Limit{}, false},
+		{"OFF", Limit{}, false},
+		{"0/1m", Limit{}, false},
+		{"10", Limit{}, true},
+		{"ten/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"10/", Limit{}, true},
+		{"10/fortnight", Limit{}, true},
+		{"10/-1m", Limit{}, true},
+		{"10000000/1s", Limit{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) err = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLimitPolicy(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		want  string
+	}{
+		{Limit{10, time.Minute}, "10;w=60"},
+		{Limit{5, 1500 * time.Millisecond}, "5;w=2"},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.Policy(); got != tt.want {
+			t.Errorf("Policy(%+v) = %q, want %q", tt.limit, got, tt.want)
+		}
+	}
+}
+
+// bucketStep is a request made advance after the previous one, and the
+// result it should get
+type bucketStep struct {
+	advance time.Duration
+	key     string
+	want    Result
+}
+
+// bucketSteps runs a two requests per second bucket through bursts, denials
+// and refills. Every store must agree on these results.
+var bucketSteps = []bucketStep{
+	{0, "a", Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond}},
+	{0, "a", Result{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second}},
+	{0, "a", Result{Allowed: false, Limit: 2, Remaining: 0, Reset: time.Second, RetryAfter: 500 * time.Millisecond}},
+	{0, "b", Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond}},
+	{500 * time.Millisecond, "a", Result{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second}},
+	{250 * time.Millisecond, "a", Result{Allowed: false, Limit: 2, Remaining: 0, Reset: 750 * time.Millisecond, RetryAfter: 250 * time.Millisecond}},
+	{2 * time.Second, "a", Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond}},
+}
+
+var bucketLimit = Limit{Requests: 2, Period: time.Second}
+
+// runBucketSteps takes from store for each step, calling advance to move its
+// clock forward first
+func runBucketSteps(t *testing.T, store Store, advance func(time.Duration)) {
+	t.Helper()
+	for i, step := range bucketSteps {
+		if step.advance > 0 {
+			advance(step.advance)
+		}
+		got, err := store.Take(context.Background(), step.key, bucketLimit)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got != step.want {
+			t.Errorf("step %d (%s): got %+v, want %+v", i, step.key, got, step.want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.RateLimitConfig
+		wantAllowed []bool
+	}{
+		{"disabled", config.RateLimitConfig{Enabled: false, Login: "1/1h"}, []bool{true, true, true}},
+		{"group off", config.RateLimitConfig{Enabled: true, Login: "off"}, []bool{true, true, true}},
+		{"limited", config.RateLimitConfig{Enabled: true, Store: "memory", Login: "2/1h"}, []bool{true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := New(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer limiter.Close()
+			for i, want := range tt.wantAllowed {
+				result, err := limiter.Take(context.Background(), GroupLogin, "203.0.113.7")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Allowed != want {
+					t.Errorf("request %d allowed = %v, want %v", i, result.Allowed, want)
+				}
+			}
+			// Other groups and keys have buckets of their own
+			if result, _ := limiter.Take(context.Background(), GroupLogin, "198.51.100.1"); !result.Allowed {
+				t.Error("other client was limited")
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.RateLimitConfig
+	}{
+		{"bad limit", config.RateLimitConfig{Enabled: true, Comments: "lots"}},
+		{"unknown store", config.RateLimitConfig{Enabled: true, Store: "memcached"}},
+		{"bad redis url", config.RateLimitConfig{Enabled: true, Store: "redis", RedisURL: "http://localhost"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces the buckets in a shared Redis database
+const redisKeyPrefix = "myblog:ratelimit:"
+
+// tokenBucketScript refills and takes from the bucket in KEYS[1] atomically.
+// ARGV[1] is the capacity and ARGV[2] the microseconds needed to refill one
+// token. The Redis clock is used so instances with skewed clocks agree. It
+// returns whether the request is allowed and the tokens left.
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or capacity
+local updated = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - updated) / interval)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) * interval / 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps buckets in Redis, or any server speaking its protocol, so
+// that every instance of the API shares the same limits
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the server at url, e.g. redis://localhost:6379/0
+func NewRedisStore(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	return &RedisStore{client: redis.NewClient(opts)}, nil
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := tokenBucketScript.Run(ctx, s.client, []string{redisKeyPrefix + key},
+		limit.Requests, limit.interval().Microseconds()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	remaining, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+	return limit.result(allowed == 1, tokens), nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	store := &RedisStore{client: redis.NewClient(&redis.Options{Addr: server.Addr()})}
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+func TestRedisStoreTake(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	now := time.Unix(1000, 0)
+	server.SetTime(now)
+
+	runBucketSteps(t, store, func(d time.Duration) {
+		now = now.Add(d)
+		server.SetTime(now)
+	})
+}
+
+func TestRedisStoreExpiry(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	server.SetTime(time.Unix(1000, 0))
+
+	tests := []struct {
+		takes   int
+		wantTTL time.Duration // refill time plus a second of slack
+	}{
+		{1, 1500 * time.Millisecond},
+		{2, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		key := "expiry"
+		server.Del(redisKeyPrefix + key)
+		for range tt.takes {
+			if _, err := store.Take(t.Context(), key, bucketLimit); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := server.TTL(redisKeyPrefix + key); got != tt.wantTTL {
+			t.Errorf("after %d requests TTL = %v, want %v", tt.takes, got, tt.wantTTL)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APITokenRepository stores hashed personal API tokens
+type APITokenRepository interface {
+	Create(token *models.APIToken) error
+	GetByHash(hash string) (*models.APIToken, error)
+	ListForUser(userID uuid.UUID) ([]*models.APIToken, error)
+	Delete(userID, id uuid.UUID) (bool, error)
+	TouchLastUsed(id uuid.UUID, at time.Time) error
+}
+
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+func (r *apiTokenRepository) Create(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *apiTokenRepository) GetByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *apiTokenRepository) ListForUser(userID uuid.UUID) ([]*models.APIToken, error) {
+	var tokens []*models.APIToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Delete revokes one of the user's tokens, reporting false if the user has no
+// token with that ID
+func (r *apiTokenRepository) Delete(userID, id uuid.UUID) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIToken{})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *apiTokenRepository) TouchLastUsed(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&models.APIToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
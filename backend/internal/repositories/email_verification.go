@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationRepository stores hashed email verification tokens
+type EmailVerificationRepository interface {
+	Create(token *models.EmailVerificationToken) error
+	GetByHash(hash string) (*models.EmailVerificationToken, error)
+	ExistsForUser(userID uuid.UUID) (bool, error)
+	DeleteForUser(userID uuid.UUID) error
+}
+
+type emailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &emailVerificationRepository{db: db}
+}
+
+func (r *emailVerificationRepository) Create(token *models.EmailVerificationToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *emailVerificationRepository) GetByHash(hash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ExistsForUser reports whether the user still has a verification link out,
+// i.e. registered and has not verified their address yet
+func (r *emailVerificationRepository) ExistsForUser(userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.EmailVerificationToken{}).Where("user_id = ?", userID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *emailVerificationRepository) DeleteForUser(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.EmailVerificationToken{}).Error
+}
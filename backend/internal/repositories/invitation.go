@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvitationRepository stores registration invitations
+type InvitationRepository interface {
+	Create(invitation *models.Invitation) error
+	GetByHash(hash string) (*models.Invitation, error)
+	List(limit, offset int) ([]*models.Invitation, int64, error)
+	Delete(id uuid.UUID) (bool, error)
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) Create(invitation *models.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+func (r *invitationRepository) GetByHash(hash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Where("token_hash = ?", hash).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) List(limit, offset int) ([]*models.Invitation, int64, error) {
+	var invitations []*models.Invitation
+	var total int64
+
+	if err := r.db.Model(&models.Invitation{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.Order("created_at DESC").Limit(limit).Offset(offset).Find(&invitations).Error
+	return invitations, total, err
+}
+
+// Delete revokes an invitation, reporting false if it does not exist
+func (r *invitationRepository) Delete(id uuid.UUID) (bool, error) {
+	result := r.db.Delete(&models.Invitation{}, id)
+	return result.RowsAffected == 1, result.Error
+}
@@ -11,6 +11,7 @@ type CategoryRepository interface {
 	Create(category *models.Category) error
 	GetByID(id uuid.UUID) (*models.Category, error)
 	GetBySlug(slug string) (*models.Category, error)
+	SlugExists(slug string, excludeID uuid.UUID) (bool, error)
 	Update(category *models.Category) error
 	Delete(id uuid.UUID) error
 	List() ([]*models.Category, error)
@@ -40,8 +41,16 @@ func (r *categoryRepository) GetBySlug(slug string) (*models.Category, error) {
 	return &category, err
 }
 
+// SlugExists reports whether a category other than excludeID uses slug,
+// including soft-deleted ones that still hold the unique index
+func (r *categoryRepository) SlugExists(slug string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
 func (r *categoryRepository) Update(category *models.Category) error {
-	return r.db.Save(category).Error
+	return updateVersioned(r.db, category, &category.Version)
 }
 
 func (r *categoryRepository) Delete(id uuid.UUID) error {
@@ -59,6 +68,7 @@ type TagRepository interface {
 	Create(tag *models.Tag) error
 	GetByID(id uuid.UUID) (*models.Tag, error)
 	GetBySlug(slug string) (*models.Tag, error)
+	SlugExists(slug string, excludeID uuid.UUID) (bool, error)
 	Update(tag *models.Tag) error
 	Delete(id uuid.UUID) error
 	List() ([]*models.Tag, error)
@@ -88,8 +98,16 @@ func (r *tagRepository) GetBySlug(slug string) (*models.Tag, error) {
 	return &tag, err
 }
 
+// SlugExists reports whether a tag other than excludeID uses slug,
+// including soft-deleted ones that still hold the unique index
+func (r *tagRepository) SlugExists(slug string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.Tag{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
 func (r *tagRepository) Update(tag *models.Tag) error {
-	return r.db.Save(tag).Error
+	return updateVersioned(r.db, tag, &tag.Version)
 }
 
 func (r *tagRepository) Delete(id uuid.UUID) error {
@@ -107,9 +125,11 @@ type CommentRepository interface {
 	Create(comment *models.Comment) error
 	GetByID(id uuid.UUID) (*models.Comment, error)
 	Update(comment *models.Comment) error
+	SetStatus(id uuid.UUID, status models.CommentStatus) error
 	Delete(id uuid.UUID) error
 	GetByPostID(postID uuid.UUID) ([]*models.Comment, error)
 	List(limit, offset int) ([]*models.Comment, int64, error)
+	CountByStatus(status models.CommentStatus) (int64, error)
 }
 
 type commentRepository struct {
@@ -131,7 +151,19 @@ func (r *commentRepository) GetByID(id uuid.UUID) (*models.Comment, error) {
 }
 
 func (r *commentRepository) Update(comment *models.Comment) error {
-	return r.db.Save(comment).Error
+	return updateVersioned(r.db, comment, &comment.Version)
+}
+
+// SetStatus moderates a comment whatever version it is at
+func (r *commentRepository) SetStatus(id uuid.UUID, status models.CommentStatus) error {
+	result := r.db.Model(&models.Comment{}).Where("id = ?", id).Updates(map[string]any{
+		"status":  status,
+		"version": gorm.Expr("version + 1"),
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
 }
 
 func (r *commentRepository) Delete(id uuid.UUID) error {
@@ -158,6 +190,12 @@ func (r *commentRepository) List(limit, offset int) ([]*models.Comment, int64, e
 	return comments, total, err
 }
 
+func (r *commentRepository) CountByStatus(status models.CommentStatus) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Comment{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
+
 // Newsletter Repository
 type NewsletterRepository interface {
 	Create(newsletter *models.Newsletter) error
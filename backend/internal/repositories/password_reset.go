@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetRepository stores hashed password reset tokens
+type PasswordResetRepository interface {
+	Create(token *models.PasswordResetToken) error
+	GetByHash(hash string) (*models.PasswordResetToken, error)
+	MarkUsed(id uuid.UUID) (bool, error)
+	DeleteForUser(userID uuid.UUID) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(token *models.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *passwordResetRepository) GetByHash(hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes the token, reporting false if it had already been used
+func (r *passwordResetRepository) MarkUsed(id uuid.UUID) (bool, error) {
+	result := r.db.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteForUser removes every reset token of the user, used or not
+func (r *passwordResetRepository) DeleteForUser(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.PasswordResetToken{}).Error
+}
@@ -1,22 +1,42 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
 	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostRepository interface {
-	Create(post *models.Post) error
-	GetByID(id uuid.UUID) (*models.Post, error)
-	GetBySlug(slug string) (*models.Post, error)
-	Update(post *models.Post) error
-	Delete(id uuid.UUID) error
-	List(limit, offset int, status models.PostStatus) ([]*models.Post, int64, error)
-	GetPublished(limit, offset int) ([]*models.Post, int64, error)
-	IncrementViewCount(id uuid.UUID) error
-	CreateWithAssociations(post *models.Post) error
-	UpdateWithAssociations(post *models.Post) error
+	Create(ctx context.Context, post *models.Post) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Post, error)
+	Update(ctx context.Context, post *models.Post) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, limit, offset int, status models.PostStatus, authorID uuid.UUID) ([]*models.Post, int64, error)
+	GetPublished(ctx context.Context, filter PostFilter, limit, offset int) ([]*models.Post, int64, error)
+	IncrementViewCount(ctx context.Context, id uuid.UUID) error
+	CreateWithAssociations(ctx context.Context, post *models.Post) error
+	UpdateWithAssociations(ctx context.Context, post *models.Post, editorID uuid.UUID) error
+	GetDueScheduled(ctx context.Context, now time.Time, limit int) ([]*models.Post, error)
+	GetPostIDBySlugHistory(ctx context.Context, slug string) (uuid.UUID, error)
+	SlugExists(ctx context.Context, slug string, excludeID uuid.UUID) (bool, error)
+	CountPublished(ctx context.Context) (int64, error)
+	ListPublishedURLs(ctx context.Context, limit, offset int) ([]*models.Post, error)
+	PublishScheduled(ctx context.Context, id uuid.UUID, publishedAt time.Time) (bool, error)
+}
+
+// PostFilter narrows down the published post listing. Zero values disable a filter.
+type PostFilter struct {
+	CategorySlug   string
+	TagSlugs       []string
+	MatchAllTags   bool // require every tag in TagSlugs instead of any of them
+	AuthorUsername string
+	From           *time.Time // inclusive lower bound on the publication date
+	To             *time.Time // exclusive upper bound on the publication date
 }
 
 type postRepository struct {
@@ -27,13 +47,13 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 	return &postRepository{db: db}
 }
 
-func (r *postRepository) Create(post *models.Post) error {
-	return r.db.Create(post).Error
+func (r *postRepository) Create(ctx context.Context, post *models.Post) error {
+	return r.db.WithContext(ctx).Create(post).Error
 }
 
-func (r *postRepository) GetByID(id uuid.UUID) (*models.Post, error) {
+func (r *postRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error) {
 	var post models.Post
-	err := r.db.Preload("Author").
+	err := r.db.WithContext(ctx).Preload("Author").
 		Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("categories.name ASC") }).
 		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") }).
 		Where("id = ?", id).First(&post).Error
@@ -43,9 +63,9 @@ func (r *postRepository) GetByID(id uuid.UUID) (*models.Post, error) {
 	return &post, nil
 }
 
-func (r *postRepository) GetBySlug(slug string) (*models.Post, error) {
+func (r *postRepository) GetBySlug(ctx context.Context, slug string) (*models.Post, error) {
 	var post models.Post
-	err := r.db.Preload("Author").
+	err := r.db.WithContext(ctx).Preload("Author").
 		Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("categories.name ASC") }).
 		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") }).
 		Where("slug = ? AND status = ?", slug, models.StatusPublished).First(&post).Error
@@ -55,19 +75,21 @@ func (r *postRepository) GetBySlug(slug string) (*models.Post, error) {
 	return &post, nil
 }
 
-func (r *postRepository) Update(post *models.Post) error {
-	return r.db.Save(post).Error
+// Update saves post, failing with ErrVersionConflict when it changed since it was read
+func (r *postRepository) Update(ctx context.Context, post *models.Post) error {
+	return updateVersioned(r.db.WithContext(ctx), post, &post.Version)
 }
 
-func (r *postRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Post{}, id).Error
+func (r *postRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.Post{}, id).Error
 }
 
-func (r *postRepository) List(limit, offset int, status models.PostStatus) ([]*models.Post, int64, error) {
+// List returns posts of any status, newest first. A nil authorID lists every author.
+func (r *postRepository) List(ctx context.Context, limit, offset int, status models.PostStatus, authorID uuid.UUID) ([]*models.Post, int64, error) {
 	var posts []*models.Post
 	var total int64
 
-	query := r.db.Model(&models.Post{}).Preload("Author").
+	query := r.db.WithContext(ctx).Model(&models.Post{}).Preload("Author").
 		Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("categories.name ASC") }).
 		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") })
 
@@ -75,6 +97,10 @@ func (r *postRepository) List(limit, offset int, status models.PostStatus) ([]*m
 		query = query.Where("status = ?", status)
 	}
 
+	if authorID != uuid.Nil {
+		query = query.Where("author_id = ?", authorID)
+	}
+
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
@@ -83,23 +109,79 @@ func (r *postRepository) List(limit, offset int, status models.PostStatus) ([]*m
 	return posts, total, err
 }
 
-func (r *postRepository) GetPublished(limit, offset int) ([]*models.Post, int64, error) {
-	return r.List(limit, offset, models.StatusPublished)
+func (r *postRepository) GetPublished(ctx context.Context, filter PostFilter, limit, offset int) ([]*models.Post, int64, error) {
+	var posts []*models.Post
+	var total int64
+
+	// Posts published before publication dates were recorded fall back to their creation date
+	publishedAt := "COALESCE(posts.published_at, posts.created_at)"
+
+	query := r.db.WithContext(ctx).Model(&models.Post{}).Where("posts.status = ?", models.StatusPublished)
+
+	if filter.CategorySlug != "" {
+		query = query.Where(`posts.id IN (
+			SELECT pc.post_id FROM post_categories pc
+			JOIN categories c ON c.id = pc.category_id
+			WHERE c.slug = ? AND c.deleted_at IS NULL)`, filter.CategorySlug)
+	}
+
+	if len(filter.TagSlugs) > 0 {
+		if filter.MatchAllTags {
+			query = query.Where(`posts.id IN (
+				SELECT pt.post_id FROM post_tags pt
+				JOIN tags t ON t.id = pt.tag_id
+				WHERE t.slug IN ? AND t.deleted_at IS NULL
+				GROUP BY pt.post_id
+				HAVING COUNT(DISTINCT t.slug) = ?)`, filter.TagSlugs, len(filter.TagSlugs))
+		} else {
+			query = query.Where(`posts.id IN (
+				SELECT pt.post_id FROM post_tags pt
+				JOIN tags t ON t.id = pt.tag_id
+				WHERE t.slug IN ? AND t.deleted_at IS NULL)`, filter.TagSlugs)
+		}
+	}
+
+	if filter.AuthorUsername != "" {
+		query = query.Where(`posts.author_id IN (
+			SELECT u.id FROM users u WHERE u.username = ? AND u.deleted_at IS NULL)`, filter.AuthorUsername)
+	}
+
+	if filter.From != nil {
+		query = query.Where(publishedAt+" >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where(publishedAt+" < ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Author").
+		Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("categories.name ASC") }).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") }).
+		Order(publishedAt + " DESC").Limit(limit).Offset(offset).Find(&posts).Error
+	return posts, total, err
 }
 
-func (r *postRepository) IncrementViewCount(id uuid.UUID) error {
-	return r.db.Model(&models.Post{}).Where("id = ?", id).
+func (r *postRepository) IncrementViewCount(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.Post{}).Where("id = ?", id).
 		UpdateColumn("view_count", gorm.Expr("view_count + ?", 1)).Error
 }
 
-func (r *postRepository) CreateWithAssociations(post *models.Post) error {
+func (r *postRepository) CreateWithAssociations(ctx context.Context, post *models.Post) error {
 	// Create the post with all associations in a transaction
-	return r.db.Transaction(func(tx *gorm.DB) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Create the post first
 		if err := tx.Create(post).Error; err != nil {
 			return err
 		}
 
+		// A live post owns its slug; it must no longer redirect elsewhere
+		if err := tx.Where("slug = ?", post.Slug).Delete(&models.PostSlugHistory{}).Error; err != nil {
+			return err
+		}
+
 		// If categories are provided, associate them
 		if len(post.Categories) > 0 {
 			if err := tx.Model(post).Association("Categories").Replace(post.Categories); err != nil {
@@ -118,11 +200,20 @@ func (r *postRepository) CreateWithAssociations(post *models.Post) error {
 	})
 }
 
-func (r *postRepository) UpdateWithAssociations(post *models.Post) error {
+func (r *postRepository) UpdateWithAssociations(ctx context.Context, post *models.Post, editorID uuid.UUID) error {
 	// Update the post with all associations in a transaction
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Update the basic post fields
-		if err := tx.Save(post).Error; err != nil {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Keep the version being overwritten as a revision
+		if err := createRevision(tx, post.ID, editorID); err != nil {
+			return err
+		}
+
+		if err := recordSlugChange(tx, post); err != nil {
+			return err
+		}
+
+		// Update the basic post fields, unless someone else did first
+		if err := updateVersioned(tx, post, &post.Version); err != nil {
 			return err
 		}
 
@@ -139,3 +230,95 @@ func (r *postRepository) UpdateWithAssociations(post *models.Post) error {
 		return nil
 	})
 }
+
+// GetPostIDBySlugHistory returns the post that previously used slug
+func (r *postRepository) GetPostIDBySlugHistory(ctx context.Context, slug string) (uuid.UUID, error) {
+	var history models.PostSlugHistory
+	if err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&history).Error; err != nil {
+		return uuid.Nil, err
+	}
+	return history.PostID, nil
+}
+
+// SlugExists reports whether a post other than excludeID uses slug, either as
+// its current slug (soft-deleted posts still hold the unique index) or as an
+// old slug that redirects to it
+func (r *postRepository) SlugExists(ctx context.Context, slug string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Post{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err = r.db.WithContext(ctx).Model(&models.PostSlugHistory{}).Where("slug = ? AND post_id <> ?", slug, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// recordSlugChange keeps the stored slug of a post in its slug history when the
+// update is about to change it. The new slug is removed from the history so a
+// post that takes back an old slug is served directly instead of redirected.
+func recordSlugChange(tx *gorm.DB, post *models.Post) error {
+	var oldSlug string
+	if err := tx.Model(&models.Post{}).Where("id = ?", post.ID).Pluck("slug", &oldSlug).Error; err != nil {
+		return err
+	}
+	if oldSlug == post.Slug {
+		return nil
+	}
+
+	if err := tx.Where("slug = ?", post.Slug).Delete(&models.PostSlugHistory{}).Error; err != nil {
+		return err
+	}
+
+	// An old slug may already be in the history, possibly for another post that
+	// has since been renamed or deleted; the most recent owner wins
+	history := &models.PostSlugHistory{PostID: post.ID, Slug: oldSlug}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"post_id", "created_at"}),
+	}).Create(history).Error
+}
+
+func (r *postRepository) CountPublished(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.Post{}).Where("status = ?", models.StatusPublished).Count(&total).Error
+	return total, err
+}
+
+// ListPublishedURLs returns published posts with only the columns needed to
+// build sitemap entries, in a stable order suitable for pagination
+func (r *postRepository) ListPublishedURLs(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	var posts []*models.Post
+	err := r.db.WithContext(ctx).Select("id", "slug", "content", "featured_img", "updated_at").
+		Where("status = ?", models.StatusPublished).
+		Order("created_at ASC, id ASC").
+		Limit(limit).Offset(offset).
+		Find(&posts).Error
+	return posts, err
+}
+
+// GetDueScheduled returns scheduled posts whose publication time has passed
+func (r *postRepository) GetDueScheduled(ctx context.Context, now time.Time, limit int) ([]*models.Post, error) {
+	var posts []*models.Post
+	err := r.db.WithContext(ctx).Where("status = ? AND publish_at <= ?", models.StatusScheduled, now).
+		Order("publish_at ASC").Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+// PublishScheduled promotes a scheduled post to published. The status check in the
+// WHERE clause makes the transition safe when several instances run the publisher;
+// the returned bool reports whether this call performed the promotion.
+func (r *postRepository) PublishScheduled(ctx context.Context, id uuid.UUID, publishedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Post{}).
+		Where("id = ? AND status = ?", id, models.StatusScheduled).
+		Updates(map[string]any{
+			"status":       models.StatusPublished,
+			"published_at": publishedAt,
+			"publish_at":   nil,
+			"version":      gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
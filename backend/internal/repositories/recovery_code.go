@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository stores hashed two-factor recovery codes
+type RecoveryCodeRepository interface {
+	ReplaceForUser(userID uuid.UUID, codes []*models.RecoveryCode) error
+	ListUnused(userID uuid.UUID) ([]*models.RecoveryCode, error)
+	MarkUsed(id uuid.UUID) (bool, error)
+	DeleteForUser(userID uuid.UUID) error
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser discards the user's previous codes and stores the new set
+func (r *recoveryCodeRepository) ReplaceForUser(userID uuid.UUID, codes []*models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *recoveryCodeRepository) ListUnused(userID uuid.UUID) ([]*models.RecoveryCode, error) {
+	var codes []*models.RecoveryCode
+	err := r.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error
+	return codes, err
+}
+
+// MarkUsed consumes the code, reporting false if it had already been used
+func (r *recoveryCodeRepository) MarkUsed(id uuid.UUID) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *recoveryCodeRepository) DeleteForUser(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errTokenAlreadyRotated = errors.New("refresh token already rotated")
+
+// RefreshTokenRepository stores hashed refresh tokens
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHash(hash string) (*models.RefreshToken, error)
+	Rotate(old *models.RefreshToken, next *models.RefreshToken) (bool, error)
+	RevokeFamily(familyID uuid.UUID) error
+	RevokeAllForUser(userID uuid.UUID) error
+	DeleteExpiredForUser(userID uuid.UUID, before time.Time) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *refreshTokenRepository) GetByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate revokes old and stores next in its place. It reports false without
+// storing anything when old was already revoked, which happens when the same
+// token is presented twice concurrently.
+func (r *refreshTokenRepository) Rotate(old *models.RefreshToken, next *models.RefreshToken) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":     time.Now(),
+				"replaced_by_id": next.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Roll back the new token; the caller treats this as reuse
+			return errTokenAlreadyRotated
+		}
+
+		rotated = true
+		return nil
+	})
+	if err == errTokenAlreadyRotated {
+		return false, nil
+	}
+	return rotated, err
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID uuid.UUID) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(userID uuid.UUID) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// DeleteExpiredForUser prunes the tokens of a user that expired before the given time
+func (r *refreshTokenRepository) DeleteExpiredForUser(userID uuid.UUID, before time.Time) error {
+	return r.db.Where("user_id = ? AND expires_at < ?", userID, before).Delete(&models.RefreshToken{}).Error
+}
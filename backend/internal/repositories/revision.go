@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PostRevisionRepository interface {
+	ListByPost(postID uuid.UUID) ([]*models.PostRevision, error)
+	GetByRevision(postID uuid.UUID, revision int) (*models.PostRevision, error)
+}
+
+type postRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostRevisionRepository(db *gorm.DB) PostRevisionRepository {
+	return &postRevisionRepository{db: db}
+}
+
+func (r *postRevisionRepository) ListByPost(postID uuid.UUID) ([]*models.PostRevision, error) {
+	var revisions []*models.PostRevision
+	err := r.db.Preload("Editor").
+		Where("post_id = ?", postID).
+		Order("revision DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *postRevisionRepository) GetByRevision(postID uuid.UUID, revision int) (*models.PostRevision, error) {
+	var rev models.PostRevision
+	err := r.db.Preload("Editor").
+		Where("post_id = ? AND revision = ?", postID, revision).
+		First(&rev).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// createRevision snapshots the stored state of a post, including its categories and
+// tags, as the next revision. It must run inside the transaction that overwrites the post.
+func createRevision(tx *gorm.DB, postID, editorID uuid.UUID) error {
+	// Serialize concurrent updates of the same post so revision numbers stay unique
+	if err := tx.Exec("SELECT id FROM posts WHERE id = ? FOR UPDATE", postID).Error; err != nil {
+		return err
+	}
+
+	var current models.Post
+	if err := tx.Preload("Categories").Preload("Tags").Where("id = ?", postID).First(&current).Error; err != nil {
+		return err
+	}
+
+	var last int
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", postID).
+		Select("COALESCE(MAX(revision), 0)").Scan(&last).Error; err != nil {
+		return err
+	}
+
+	revision := &models.PostRevision{
+		PostID:      current.ID,
+		Revision:    last + 1,
+		Title:       current.Title,
+		Slug:        current.Slug,
+		Excerpt:     current.Excerpt,
+		Content:     current.Content,
+		FeaturedImg: current.FeaturedImg,
+		Status:      current.Status,
+		CategoryIDs: make([]string, 0, len(current.Categories)),
+		TagIDs:      make([]string, 0, len(current.Tags)),
+	}
+	if editorID != uuid.Nil {
+		revision.EditorID = &editorID
+	}
+	for _, category := range current.Categories {
+		revision.CategoryIDs = append(revision.CategoryIDs, category.ID.String())
+	}
+	for _, tag := range current.Tags {
+		revision.TagIDs = append(revision.TagIDs, tag.ID.String())
+	}
+
+	return tx.Create(revision).Error
+}
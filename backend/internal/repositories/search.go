@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"html"
+	"strings"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Markers used by ts_headline. They are stripped from the text handed to
+// ts_headline, so the snippet can be HTML-escaped before the markers are
+// turned into <mark> tags.
+const (
+	headlineStart = "\x02"
+	headlineStop  = "\x03"
+)
+
+type SearchRepository interface {
+	SearchPosts(query string, limit, offset int) ([]*models.PostSearchResult, int64, error)
+}
+
+type searchRepository struct {
+	db       *gorm.DB
+	language string
+}
+
+func NewSearchRepository(db *gorm.DB, language string) SearchRepository {
+	return &searchRepository{db: db, language: language}
+}
+
+type searchHit struct {
+	ID      uuid.UUID
+	Rank    float64
+	Snippet string
+}
+
+// SearchPosts runs a ranked full-text search over published posts using the
+// weighted search_vector column created by the migrations
+func (r *searchRepository) SearchPosts(query string, limit, offset int) ([]*models.PostSearchResult, int64, error) {
+	base := r.db.Table("posts, websearch_to_tsquery(?::regconfig, ?) AS q", r.language, query).
+		Where("posts.deleted_at IS NULL AND posts.status = ? AND posts.search_vector @@ q", models.StatusPublished)
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.PostSearchResult{}, 0, nil
+	}
+
+	options := "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+
+	var hits []searchHit
+	err := base.Session(&gorm.Session{}).
+		Select(`posts.id AS id,
+			ts_rank_cd(posts.search_vector, q) AS rank,
+			ts_headline(?::regconfig, translate(coalesce(posts.excerpt, '') || ' ' || coalesce(posts.content, ''), ?, ''), q, ?) AS snippet`,
+			r.language, headlineStart+headlineStop, options).
+		Order("rank DESC, posts.published_at DESC").
+		Limit(limit).Offset(offset).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	var posts []*models.Post
+	err = r.db.Preload("Author").
+		Preload("Categories", func(db *gorm.DB) *gorm.DB { return db.Order("categories.name ASC") }).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") }).
+		Where("id IN ?", ids).Find(&posts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[uuid.UUID]*models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	// Keep the ranking order from the search query
+	results := make([]*models.PostSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		post, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, &models.PostSearchResult{
+			Post:    post,
+			Rank:    hit.Rank,
+			Snippet: highlightSnippet(hit.Snippet),
+		})
+	}
+
+	return results, total, nil
+}
+
+// highlightSnippet escapes a ts_headline result and converts its markers to <mark> tags
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>").Replace(escaped)
+}
@@ -1,19 +1,28 @@
 package repositories
 
 import (
+	"errors"
+	"time"
+
 	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
 	Create(user *models.User) error
+	Register(user *models.User, invitationID *uuid.UUID) (bool, error)
 	GetByID(id uuid.UUID) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	GetByUsername(username string) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id uuid.UUID) error
 	List(limit, offset int) ([]*models.User, int64, error)
+	UseTOTPStep(id uuid.UUID, step int64) (bool, error)
+	RecordLoginFailure(id uuid.UUID, at, forgetBefore time.Time) (int, error)
+	LockUntil(id uuid.UUID, until time.Time) error
+	ClearLoginFailures(id uuid.UUID) error
 }
 
 type userRepository struct {
@@ -28,6 +37,37 @@ func (r *userRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
+var errInvitationAlreadyUsed = errors.New("invitation already used")
+
+// Register stores a self-registered user. When invitationID is set the
+// invitation is consumed in the same transaction; Register reports false
+// without storing anything when it had already been used.
+func (r *userRepository) Register(user *models.User, invitationID *uuid.UUID) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if invitationID == nil {
+			return nil
+		}
+
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND used_at IS NULL", *invitationID).
+			Updates(map[string]any{"used_at": time.Now(), "used_by_id": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvitationAlreadyUsed
+		}
+		return nil
+	})
+	if err == errInvitationAlreadyUsed {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("id = ?", id).First(&user).Error
@@ -74,3 +114,40 @@ func (r *userRepository) List(limit, offset int) ([]*models.User, int64, error)
 	err := r.db.Limit(limit).Offset(offset).Find(&users).Error
 	return users, total, err
 }
+
+// RecordLoginFailure counts a failed login and returns the number of
+// consecutive failures. The count starts over when the previous failure
+// happened before forgetBefore.
+func (r *userRepository) RecordLoginFailure(id uuid.UUID, at, forgetBefore time.Time) (int, error) {
+	var user models.User
+	err := r.db.Model(&user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}}}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]any{
+			"failed_login_attempts": gorm.Expr("CASE WHEN last_failed_login_at IS NULL OR last_failed_login_at < ? THEN 1 ELSE failed_login_attempts + 1 END", forgetBefore),
+			"last_failed_login_at":  at,
+		}).Error
+	return user.FailedLoginAttempts, err
+}
+
+func (r *userRepository) LockUntil(id uuid.UUID, until time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).UpdateColumn("locked_until", until).Error
+}
+
+// ClearLoginFailures unlocks the account and resets its failure count
+func (r *userRepository) ClearLoginFailures(id uuid.UUID) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).UpdateColumns(map[string]any{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	}).Error
+}
+
+// UseTOTPStep records step as the last accepted TOTP time step. It reports
+// false when that step or a later one was already used.
+func (r *userRepository) UseTOTPStep(id uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
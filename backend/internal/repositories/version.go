@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict is returned when a record was updated by someone else
+// after the version being saved was read
+var ErrVersionConflict = errors.New("record was modified since it was read")
+
+// updateVersioned saves every column of model like Save, bumping *version,
+// but only when the row still has the version model was read with.
+// Associations are left alone.
+func updateVersioned(db *gorm.DB, model any, version *int) error {
+	read := *version
+	*version = read + 1
+
+	result := db.Model(model).Where("version = ?", read).Select("*").Omit(clause.Associations).Updates(model)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = read
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	updateCategory := regexp.QuoteMeta(`UPDATE "categories" SET "name"=$1,"slug"=$2,"description"=$3,"color"=$4,"version"=$5,"created_at"=$6,"updated_at"=$7,"deleted_at"=$8 WHERE version = $9 AND "categories"."deleted_at" IS NULL AND "id" = $10`)
+
+	tests := []struct {
+		name        string
+		rows        int64
+		execErr     error
+		wantErr     error
+		wantVersion int
+	}{
+		{"current version", 1, nil, nil, 4},
+		{"stale version", 0, nil, ErrVersionConflict, 3},
+		{"database error", 0, errors.New("connection reset"), nil, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			category := &models.Category{ID: uuid.New(), Name: "Go", Slug: "go", Color: "#00ADD8", Version: 3}
+
+			exec := mock.ExpectExec(updateCategory).WithArgs(
+				"Go", "go", "", "#00ADD8",
+				4, // the new version is saved
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				3, // only over the version that was read
+				category.ID,
+			)
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tt.rows))
+			}
+
+			err := updateVersioned(db, category, &category.Version)
+			switch {
+			case tt.execErr != nil:
+				if !errors.Is(err, tt.execErr) {
+					t.Errorf("err = %v, want %v", err, tt.execErr)
+				}
+			case err != tt.wantErr:
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if category.Version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", category.Version, tt.wantVersion)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
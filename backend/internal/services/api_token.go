@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// APITokenPrefix starts every personal API token, which tells them apart from
+// JWTs in the Authorization header
+const APITokenPrefix = "mbp_"
+
+// lastUsedResolution limits how often last_used_at is written for a token
+// that is used on every request of a CI run
+const lastUsedResolution = time.Minute
+
+// API token errors
+var (
+	ErrInvalidAPIToken  = errors.New("invalid API token")
+	ErrInvalidScope     = errors.New("invalid scope")
+	ErrAPITokenNotFound = errors.New("API token not found")
+)
+
+type APITokenService interface {
+	Create(userID uuid.UUID, req *CreateAPITokenRequest) (*models.APIToken, string, error)
+	List(userID uuid.UUID) ([]*models.APIToken, error)
+	Revoke(userID, id uuid.UUID) error
+	Authenticate(token string) (*models.APIToken, *models.User, error)
+}
+
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=3650"` // 0 never expires
+}
+
+type apiTokenService struct {
+	tokenRepo repositories.APITokenRepository
+	userRepo  repositories.UserRepository
+}
+
+func NewAPITokenService(tokenRepo repositories.APITokenRepository, userRepo repositories.UserRepository) APITokenService {
+	return &apiTokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// Create issues a new token. The plain token is returned only here; afterwards
+// just its hash is known.
+func (s *apiTokenService) Create(userID uuid.UUID, req *CreateAPITokenRequest) (*models.APIToken, string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scopes, err := tokenScopes(user.Role, req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw, _, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	raw = APITokenPrefix + raw
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    raw[:len(APITokenPrefix)+8],
+		TokenHash: hashToken(raw),
+		Scopes:    scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenRepo.Create(token); err != nil {
+		return nil, "", err
+	}
+	return token, raw, nil
+}
+
+func (s *apiTokenService) List(userID uuid.UUID) ([]*models.APIToken, error) {
+	return s.tokenRepo.ListForUser(userID)
+}
+
+func (s *apiTokenService) Revoke(userID, id uuid.UUID) error {
+	deleted, err := s.tokenRepo.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a token from the Authorization header to its owner.
+// Tokens of deactivated users stop working along with their sessions.
+func (s *apiTokenService) Authenticate(raw string) (*models.APIToken, *models.User, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	token, err := s.tokenRepo.GetByHash(hashToken(raw))
+	if err != nil {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil || !user.IsActive {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := s.tokenRepo.TouchLastUsed(token.ID, now); err != nil {
+			logger.WithError(err).Warn("Failed to record API token use", map[string]any{
+				"token_id": token.ID.String(),
+			})
+		}
+		token.LastUsedAt = &now
+	}
+
+	return token, user, nil
+}
+
+// tokenScopes validates requested scopes, refusing those that unlock nothing
+// the role is allowed to do
+func tokenScopes(role models.UserRole, requested []string) ([]string, error) {
+	seen := make(map[models.APITokenScope]bool)
+	var scopes []string
+	for _, name := range requested {
+		scope := models.APITokenScope(strings.TrimSpace(name))
+		if !scope.Valid() {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, name)
+		}
+		if !roleCanUseScope(role, scope) {
+			return nil, fmt.Errorf("%w: %q is not available to the %s role", ErrInvalidScope, name, role)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, string(scope))
+		}
+	}
+	return scopes, nil
+}
+
+func roleCanUseScope(role models.UserRole, scope models.APITokenScope) bool {
+	if scope == models.ScopePostsRead {
+		return true
+	}
+	for _, p := range scope.Permissions() {
+		if role.Can(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+)
+
+func TestTokenScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      models.UserRole
+		requested []string
+		want      []string
+		wantErr   bool
+	}{
+		{"none", models.RoleAuthor, nil, nil, false},
+		{"read for contributor", models.RoleContributor, []string{"posts:read"}, []string{"posts:read"}, false},
+		{"trims and dedupes", models.RoleAuthor, []string{" posts:write", "posts:write ", "images:write"}, []string{"posts:write", "images:write"}, false},
+		{"editor taxonomy", models.RoleEditor, []string{"taxonomy:write", "comments:write"}, []string{"taxonomy:write", "comments:write"}, false},
+		{"admin newsletter", models.RoleAdmin, []string{"newsletter:write"}, []string{"newsletter:write"}, false},
+		{"author taxonomy", models.RoleAuthor, []string{"taxonomy:write"}, nil, true},
+		{"editor newsletter", models.RoleEditor, []string{"newsletter:write"}, nil, true},
+		{"unknown scope", models.RoleAdmin, []string{"users:write"}, nil, true},
+		{"one bad scope fails all", models.RoleAdmin, []string{"posts:read", "bogus"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenScopes(tt.role, tt.requested)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidScope) {
+					t.Fatalf("err = %v, want ErrInvalidScope", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("scopes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
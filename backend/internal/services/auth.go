@@ -1,10 +1,17 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/mailer"
 	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/chmenegatti/myBlog/internal/repositories"
 	"github.com/golang-jwt/jwt/v5"
@@ -13,70 +20,190 @@ import (
 )
 
 type AuthService interface {
-	Login(email, password string) (*LoginResponse, error)
+	Login(email, password string, client ClientInfo) (*LoginResponse, error)
 	Register(req *RegisterRequest) (*models.User, error)
-	RefreshToken(tokenString string) (*LoginResponse, error)
+	RefreshToken(refreshToken string, client ClientInfo) (*LoginResponse, error)
+	Logout(refreshToken string) error
+	LogoutAll(userID uuid.UUID) error
+	VerifyEmail(token string) error
+	ResendVerification(email string) error
+	ForgotPassword(email string) error
+	ResetPassword(token, newPassword string) error
+	LoginTwoFactor(challengeToken, code, recoveryCode string, client ClientInfo) (*LoginResponse, error)
+	LoginAfterEnrollment(userID uuid.UUID, client ClientInfo) (*LoginResponse, error)
+	SetupTOTP(userID uuid.UUID) (*TOTPSetup, error)
+	ConfirmTOTP(userID uuid.UUID, code string) ([]string, error)
+	DisableTOTP(userID uuid.UUID, password, code string) error
+	RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error)
+	UnlockAccount(userID uuid.UUID) error
 }
 
+// Refresh token errors
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// Registration errors
+var (
+	ErrRegistrationClosed       = errors.New("registration is closed")
+	ErrInvitationRequired       = errors.New("registration requires an invitation")
+	ErrInvalidInvitation        = errors.New("invalid or expired invitation")
+	ErrInvalidVerificationToken = errors.New("invalid or expired email verification link")
+	ErrEmailNotVerified         = errors.New("email address has not been verified")
+)
+
+// ErrInvalidResetToken is returned for unknown, expired or already used password reset tokens
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
 type authService struct {
-	userRepo repositories.UserRepository
-	jwtCfg   config.JWTConfig
+	userRepo          repositories.UserRepository
+	refreshTokenRepo  repositories.RefreshTokenRepository
+	passwordResetRepo repositories.PasswordResetRepository
+	recoveryCodeRepo  repositories.RecoveryCodeRepository
+	verificationRepo  repositories.EmailVerificationRepository
+	invitationRepo    repositories.InvitationRepository
+	mailer            mailer.Mailer
+	jwtCfg            config.JWTConfig
+	keys              *KeySet
+	authCfg           config.AuthConfig
+	issuer            string // shown next to the account in authenticator apps
+	urls              SiteURLs
+	accountPolicy     lockoutPolicy
+	ipAttempts        *attemptTracker
+	unknownAttempts   *attemptTracker // failed logins per email without an account
+}
+
+// ClientInfo describes the client a session is opened from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
 }
 
+// LoginResponse either carries the session tokens or, when a second factor is
+// needed, a challenge token to complete the login with
 type LoginResponse struct {
-	User  *models.User `json:"user"`
-	Token string       `json:"token"`
+	User         *models.User `json:"user,omitempty"`
+	Token        string       `json:"token,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	ExpiresIn    int          `json:"expires_in"` // seconds until Token or ChallengeToken expires
+
+	TwoFactorRequired      bool   `json:"two_factor_required,omitempty"`       // send a code to /auth/login/2fa
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"` // enroll through /auth/2fa/setup first
+	ChallengeToken         string `json:"challenge_token,omitempty"`
 }
 
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-	Name     string `json:"name" binding:"required"`
+	Username        string `json:"username" binding:"required"`
+	Email           string `json:"email" binding:"required,email"`
+	Password        string `json:"password" binding:"required,min=6"`
+	Name            string `json:"name" binding:"required"`
+	InvitationToken string `json:"invitation_token"`
 }
 
+// AudienceSession is the aud of access tokens. Services verifying tokens with
+// the published keys must require it, along with the issuer, or they would
+// also accept two-factor challenges.
+const AudienceSession = "session"
+
 type JWTClaims struct {
-	UserID uuid.UUID       `json:"user_id"`
-	Role   models.UserRole `json:"role"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Role      models.UserRole `json:"role"`
+	SessionID uuid.UUID       `json:"sid"` // refresh token family the access token was issued for
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(userRepo repositories.UserRepository, jwtCfg config.JWTConfig) AuthService {
+func NewAuthService(userRepo repositories.UserRepository, refreshTokenRepo repositories.RefreshTokenRepository, passwordResetRepo repositories.PasswordResetRepository, recoveryCodeRepo repositories.RecoveryCodeRepository, verificationRepo repositories.EmailVerificationRepository, invitationRepo repositories.InvitationRepository, mailer mailer.Mailer, keys *KeySet, jwtCfg config.JWTConfig, authCfg config.AuthConfig, site config.SiteConfig) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		jwtCfg:   jwtCfg,
+		userRepo:          userRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		passwordResetRepo: passwordResetRepo,
+		recoveryCodeRepo:  recoveryCodeRepo,
+		verificationRepo:  verificationRepo,
+		invitationRepo:    invitationRepo,
+		mailer:            mailer,
+		jwtCfg:            jwtCfg,
+		keys:              keys,
+		authCfg:           authCfg,
+		issuer:            site.Title,
+		urls:              NewSiteURLs(site.URL),
+		accountPolicy:     newLockoutPolicy(authCfg.LoginMaxAttempts, authCfg),
+		ipAttempts:        newAttemptTracker(newLockoutPolicy(authCfg.LoginIPMaxAttempts, authCfg)),
+		unknownAttempts:   newAttemptTracker(newLockoutPolicy(authCfg.LoginMaxAttempts, authCfg)),
 	}
 }
 
-func (s *authService) Login(email, password string) (*LoginResponse, error) {
+func (s *authService) Login(email, password string, client ClientInfo) (*LoginResponse, error) {
+	now := time.Now()
+	if err := s.checkLoginAllowed(nil, client, now); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, s.unknownAccountLoginFailed(email, client, now, errors.New("invalid credentials"))
+	}
+
+	if err := s.checkLoginAllowed(user, client, now); err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, s.loginFailed(user, client, errors.New("invalid credentials"))
 	}
 
 	if !user.IsActive {
+		if pending, _ := s.verificationRepo.ExistsForUser(user.ID); pending && user.EmailVerifiedAt == nil {
+			return nil, ErrEmailNotVerified
+		}
 		return nil, errors.New("account is deactivated")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+	// The password alone is not enough when a second factor is enabled or required
+	if user.TOTPEnabled {
+		return s.challengeResponse(user, audienceTwoFactorLogin)
+	}
+	if s.twoFactorRequired(user) {
+		return s.challengeResponse(user, audienceTwoFactorEnroll)
 	}
 
-	token, err := s.generateToken(user)
-	if err != nil {
+	return s.startSession(user, client)
+}
+
+// startSession opens a new session for an authenticated user
+func (s *authService) startSession(user *models.User, client ClientInfo) (*LoginResponse, error) {
+	// Failed attempts only count until a login completes, second factor included
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ClearLoginFailures(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Logging in is a good moment to drop the user's dead sessions
+	if err := s.refreshTokenRepo.DeleteExpiredForUser(user.ID, time.Now()); err != nil {
 		return nil, err
 	}
 
-	// Remove password from response
-	user.Password = ""
+	// Every login starts a new session, i.e. a new refresh token family
+	rawToken, refreshToken, err := s.newRefreshToken(user.ID, uuid.New(), client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshTokenRepo.Create(refreshToken); err != nil {
+		return nil, err
+	}
 
-	return &LoginResponse{
-		User:  user,
-		Token: token,
-	}, nil
+	return s.loginResponse(user, refreshToken.FamilyID, rawToken)
 }
 
+// Register creates an inactive account according to the registration mode
+// and emails a link to verify the address, which activates it
 func (s *authService) Register(req *RegisterRequest) (*models.User, error) {
+	invitation, err := s.registrationInvitation(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	if _, err := s.userRepo.GetByEmail(req.Email); err == nil {
 		return nil, errors.New("email already exists")
@@ -92,16 +219,37 @@ func (s *authService) Register(req *RegisterRequest) (*models.User, error) {
 		return nil, err
 	}
 
+	role := models.RoleContributor // self-registered users need an editor to publish
+	if invitation != nil {
+		role = invitation.Role
+	}
+
+	// The account stays inactive until the email is verified
 	user := &models.User{
+		ID:       uuid.New(),
 		Username: req.Username,
 		Email:    req.Email,
 		Password: string(hashedPassword),
 		Name:     req.Name,
-		Role:     models.RoleAuthor,
-		IsActive: true,
+		Role:     role,
+		IsActive: false,
+	}
+
+	// The invitation is consumed together with the user, so it can neither be
+	// used twice nor be lost to a failed insert
+	var invitationID *uuid.UUID
+	if invitation != nil {
+		invitationID = &invitation.ID
+	}
+	registered, err := s.userRepo.Register(user, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if !registered {
+		return nil, ErrInvalidInvitation
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.sendVerificationEmail(user); err != nil {
 		return nil, err
 	}
 
@@ -110,22 +258,165 @@ func (s *authService) Register(req *RegisterRequest) (*models.User, error) {
 	return user, nil
 }
 
-func (s *authService) RefreshToken(tokenString string) (*LoginResponse, error) {
-	claims, err := ValidateJWT(tokenString, s.jwtCfg.Secret)
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is revoked; presenting it again means it
+// was copied, so the whole session is revoked and has to log in again.
+func (s *authService) RefreshToken(refreshToken string, client ClientInfo) (*LoginResponse, error) {
+	stored, err := s.refreshTokenRepo.GetByHash(hashToken(refreshToken))
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.RevokedAt != nil {
+		if stored.ReplacedByID != nil {
+			if err := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	user, err := s.userRepo.GetByID(claims.UserID)
+	user, err := s.userRepo.GetByID(stored.UserID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
 	if !user.IsActive {
+		if err := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("account is deactivated")
 	}
 
-	token, err := s.generateToken(user)
+	rawToken, next, err := s.newRefreshToken(user.ID, stored.FamilyID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.refreshTokenRepo.Rotate(stored, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request rotated the same token first
+		if err := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return s.loginResponse(user, stored.FamilyID, rawToken)
+}
+
+// Logout revokes the session the refresh token belongs to
+func (s *authService) Logout(refreshToken string) error {
+	stored, err := s.refreshTokenRepo.GetByHash(hashToken(refreshToken))
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	return s.refreshTokenRepo.RevokeFamily(stored.FamilyID)
+}
+
+// LogoutAll revokes every session of the user. Access tokens already issued
+// stay valid until they expire, which is why they are short-lived.
+func (s *authService) LogoutAll(userID uuid.UUID) error {
+	return s.refreshTokenRepo.RevokeAllForUser(userID)
+}
+
+// ForgotPassword emails a password reset link to the user with that email.
+// Unknown and deactivated accounts are silently ignored so the endpoint cannot
+// be used to find out which emails are registered.
+func (s *authService) ForgotPassword(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || !user.IsActive {
+		return nil
+	}
+
+	// Only the most recent link works
+	if err := s.passwordResetRepo.DeleteForUser(user.ID); err != nil {
+		return err
+	}
+
+	rawToken, tokenHash, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiration := time.Duration(s.authCfg.PasswordResetExpiration) * time.Minute
+	if err := s.passwordResetRepo.Create(&models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(expiration),
+	}); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your account. If it was you, open the link below to choose a new password:\n\n"+
+			"%s\n\n"+
+			"The link expires in %d minutes and can only be used once. If you did not ask for it, you can ignore this email.\n",
+			user.Name, s.urls.PasswordReset(rawToken), s.authCfg.PasswordResetExpiration),
+	}
+
+	// The mailer sends in the background, so the response time does not reveal whether the account exists
+	if err := s.mailer.Send(msg); err != nil {
+		logger.WithError(err).Error("Failed to send password reset email", map[string]any{
+			"user_id": user.ID.String(),
+		})
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password using an emailed reset token. The token is
+// consumed and every session of the user is revoked.
+func (s *authService) ResetPassword(token, newPassword string) error {
+	stored, err := s.passwordResetRepo.GetByHash(hashToken(token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil || !user.IsActive {
+		return ErrInvalidResetToken
+	}
+
+	consumed, err := s.passwordResetRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if err := s.passwordResetRepo.DeleteForUser(user.ID); err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeAllForUser(user.ID)
+}
+
+func (s *authService) loginResponse(user *models.User, sessionID uuid.UUID, refreshToken string) (*LoginResponse, error) {
+	token, err := s.generateToken(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -134,30 +425,70 @@ func (s *authService) RefreshToken(tokenString string) (*LoginResponse, error) {
 	user.Password = ""
 
 	return &LoginResponse{
-		User:  user,
-		Token: token,
+		User:         user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.jwtCfg.AccessExpiration * 60,
 	}, nil
 }
 
-func (s *authService) generateToken(user *models.User) (string, error) {
+func (s *authService) generateToken(user *models.User, sessionID uuid.UUID) (string, error) {
 	claims := JWTClaims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.jwtCfg.Expiration) * time.Hour)),
+			Issuer:    s.keys.issuer,
+			Audience:  jwt.ClaimStrings{AudienceSession},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.jwtCfg.AccessExpiration) * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtCfg.Secret))
+	return s.keys.Sign(claims)
+}
+
+// newRefreshToken generates an opaque refresh token, returning it together
+// with the record to store, which only holds its hash
+func (s *authService) newRefreshToken(userID, familyID uuid.UUID, client ClientInfo) (string, *models.RefreshToken, error) {
+	raw, hash, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return raw, &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(time.Duration(s.jwtCfg.RefreshExpiration) * time.Hour),
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+	}, nil
+}
+
+// generateOpaqueToken returns a random URL-safe token and its hash for storage
+func generateOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, hashToken(raw), nil
+}
+
+// hashToken hashes an opaque token for storage. Tokens carry 256 bits of
+// randomness, so a fast unsalted hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // ValidateJWT validates and parses JWT token
-func ValidateJWT(tokenString string, secret string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+func ValidateJWT(tokenString string, keys *KeySet) (*JWTClaims, error) {
+	// The audience keeps two-factor challenges, signed with the same keys, from
+	// being accepted as access tokens
+	token, err := keys.parse(tokenString, &JWTClaims{}, jwt.WithAudience(AudienceSession))
 
 	if err != nil {
 		return nil, err
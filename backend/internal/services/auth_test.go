@@ -0,0 +1,51 @@
+package services
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestHashToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"empty", "", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sentence", "The quick brown fox jumps over the lazy dog", "d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hashToken(tt.token)
+			if got != tt.want {
+				t.Errorf("hashToken(%q) = %q, want %q", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateOpaqueToken(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		raw, hash, err := generateOpaqueToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil {
+			t.Fatalf("token %q is not unpadded base64url: %v", raw, err)
+		}
+		if len(decoded) != 32 {
+			t.Errorf("token carries %d bytes of randomness, want 32", len(decoded))
+		}
+		if hash != hashToken(raw) {
+			t.Errorf("hash of %q = %q, want hashToken of the token", raw, hash)
+		}
+		if seen[raw] {
+			t.Fatalf("token %q generated twice", raw)
+		}
+		seen[raw] = true
+	}
+}
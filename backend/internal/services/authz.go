@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrForbidden is returned when the acting user may not perform an operation
+var ErrForbidden = errors.New("you do not have permission to perform this action")
+
+// Actor is the authenticated user an operation is performed for
+type Actor struct {
+	UserID uuid.UUID
+	Role   models.UserRole
+	Token  *models.APIToken // personal API token used, nil for login sessions
+}
+
+// Can reports whether the role is granted the permission and, when acting
+// through an API token, the token's scopes unlock it
+func (a Actor) Can(p models.Permission) bool {
+	if a.Token != nil && !a.Token.Allows(p) {
+		return false
+	}
+	return a.Role.Can(p)
+}
+
+// canViewPost: editors see every post, everyone else only their own
+func (a Actor) canViewPost(post *models.Post) bool {
+	return a.Can(models.PermManagePosts) || post.AuthorID == a.UserID
+}
+
+// canEditPost: editors edit every post, authors their own, contributors their
+// own drafts only since anything past a draft has been through an editor
+func (a Actor) canEditPost(post *models.Post) bool {
+	if a.Can(models.PermManagePosts) {
+		return true
+	}
+	if post.AuthorID != a.UserID || !a.Can(models.PermCreatePosts) {
+		return false
+	}
+	return a.Can(models.PermPublishPosts) || post.Status == models.StatusDraft
+}
+
+// canSetStatus reports whether the actor may move one of their posts to status
+func (a Actor) canSetStatus(status models.PostStatus) bool {
+	switch status {
+	case models.StatusPublished, models.StatusScheduled:
+		return a.Can(models.PermPublishPosts) || a.Can(models.PermManagePosts)
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package services
+
+import "strings"
+
+// DiffOp identifies the kind of change a DiffLine represents
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine is a single line of a line-level diff. OldLine and NewLine are
+// 1-based line numbers and are zero when the line does not exist on that side.
+type DiffLine struct {
+	Op      DiffOp `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+// DiffText computes a line-level diff between two texts
+func DiffText(oldText, newText string) []DiffLine {
+	return diffLines(splitLines(oldText), splitLines(newText))
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines implements the linear-space variant of the Myers O(ND) diff
+// algorithm: it finds the middle snake of the shortest edit script and
+// recurses on either side of it, so memory stays proportional to the input.
+// Lines are compared by ID rather than by text.
+func diffLines(a, b []string) []DiffLine {
+	ids := make(map[string]int, len(a)+len(b))
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+
+	size := len(a) + len(b) + 4
+	d := &differ{
+		a:      a,
+		b:      b,
+		aIDs:   intern(a),
+		bIDs:   intern(b),
+		vf:     make([]int, size),
+		vb:     make([]int, size),
+		result: make([]DiffLine, 0, len(a)+len(b)),
+	}
+	d.compare(0, len(a), 0, len(b))
+	return d.result
+}
+
+type differ struct {
+	a, b       []string
+	aIDs, bIDs []int
+	vf, vb     []int // furthest reaching x per diagonal, forward and backward
+	result     []DiffLine
+}
+
+func (d *differ) equal(x, y int) {
+	d.result = append(d.result, DiffLine{Op: DiffEqual, OldLine: x + 1, NewLine: y + 1, Text: d.a[x]})
+}
+
+// compare appends the diff of a[aLo:aHi] and b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// The common prefix and suffix need no search
+	for aLo < aHi && bLo < bHi && d.aIDs[aLo] == d.bIDs[bLo] {
+		d.equal(aLo, bLo)
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.aIDs[aHi-1-suffix] == d.bIDs[bHi-1-suffix] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.result = append(d.result, DiffLine{Op: DiffInsert, NewLine: y + 1, Text: d.b[y]})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.result = append(d.result, DiffLine{Op: DiffDelete, OldLine: x + 1, Text: d.a[x]})
+		}
+	default:
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.equal(x, y)
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.equal(aHi+i, bHi+i)
+	}
+}
+
+// middleSnake runs the search from both ends of a[aLo:aHi] and b[bLo:bHi]
+// until the paths meet, and returns the start (x, y) and end (u, v) of the
+// diagonal where they overlap. Both sides must be non-empty and differ in
+// their first and last lines, so each half holds fewer edits than the whole.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	vf, vb := d.vf, d.vb
+	vf[offset+1] = 0
+	vb[offset+1] = 0
+
+	for step := 0; step <= limit; step++ {
+		// Forward paths from (aLo, bLo)
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.aIDs[aLo+x] == d.bIDs[bLo+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+
+			if c := delta - k; odd && c >= -(step-1) && c <= step-1 && x+vb[offset+c] >= n {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+
+		// Backward paths from (aHi, bHi), in coordinates counted from the end
+		for c := -step; c <= step; c += 2 {
+			var x int
+			if c == -step || (c != step && vb[offset+c-1] < vb[offset+c+1]) {
+				x = vb[offset+c+1]
+			} else {
+				x = vb[offset+c-1] + 1
+			}
+			y := x - c
+			x0, y0 := x, y
+			for x < n && y < m && d.aIDs[aHi-1-x] == d.bIDs[bHi-1-y] {
+				x++
+				y++
+			}
+			vb[offset+c] = x
+
+			if k := delta - c; !odd && k >= -step && k <= step && x+vf[offset+k] >= n {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func eq(old, new int, text string) DiffLine {
+	return DiffLine{Op: DiffEqual, OldLine: old, NewLine: new, Text: text}
+}
+
+func ins(new int, text string) DiffLine {
+	return DiffLine{Op: DiffInsert, NewLine: new, Text: text}
+}
+
+func del(old int, text string) DiffLine {
+	return DiffLine{Op: DiffDelete, OldLine: old, Text: text}
+}
+
+func TestDiffText(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []DiffLine
+	}{
+		{"both empty", "", "", []DiffLine{}},
+		{"identical", "a\nb\n", "a\nb", []DiffLine{eq(1, 1, "a"), eq(2, 2, "b")}},
+		{"insert only", "", "a\nb", []DiffLine{ins(1, "a"), ins(2, "b")}},
+		{"delete only", "a\nb", "", []DiffLine{del(1, "a"), del(2, "b")}},
+		{"CRLF matches LF", "a\r\nb\r\n", "a\nb\n", []DiffLine{eq(1, 1, "a"), eq(2, 2, "b")}},
+		{
+			"shared prefix and suffix",
+			"title\nold body\nfooter",
+			"title\nnew body\nfooter",
+			[]DiffLine{eq(1, 1, "title"), del(2, "old body"), ins(2, "new body"), eq(3, 3, "footer")},
+		},
+		{
+			"line numbers shift after an insert",
+			"a\nb\nc",
+			"a\nx\ny\nb\nc",
+			[]DiffLine{eq(1, 1, "a"), ins(2, "x"), ins(3, "y"), eq(2, 4, "b"), eq(3, 5, "c")},
+		},
+		{
+			"line numbers shift after a delete",
+			"a\nx\nb\nc",
+			"a\nb\nc\nd",
+			[]DiffLine{eq(1, 1, "a"), del(2, "x"), eq(3, 2, "b"), eq(4, 3, "c"), ins(4, "d")},
+		},
+		{
+			"nothing in common",
+			"a\nb",
+			"c\nd",
+			[]DiffLine{del(1, "a"), del(2, "b"), ins(1, "c"), ins(2, "d")},
+		},
+		{
+			"moved line",
+			"a\nb\nc\nd",
+			"b\nc\na\nd",
+			[]DiffLine{del(1, "a"), eq(2, 1, "b"), eq(3, 2, "c"), ins(3, "a"), eq(4, 4, "d")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffText(tt.old, tt.new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffText =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+// lcsLength is the textbook dynamic programming answer the diff must match
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLinesIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := range 500 {
+		a, b := randomLines(), randomLines()
+		diff := diffLines(a, b)
+
+		var oldSide, newSide []string
+		equal := 0
+		for _, line := range diff {
+			switch line.Op {
+			case DiffEqual:
+				equal++
+				oldSide = append(oldSide, line.Text)
+				newSide = append(newSide, line.Text)
+				if a[line.OldLine-1] != line.Text || b[line.NewLine-1] != line.Text {
+					t.Fatalf("case %d: equal line %+v has wrong line numbers", i, line)
+				}
+			case DiffDelete:
+				oldSide = append(oldSide, line.Text)
+				if a[line.OldLine-1] != line.Text {
+					t.Fatalf("case %d: deleted line %+v has the wrong line number", i, line)
+				}
+			case DiffInsert:
+				newSide = append(newSide, line.Text)
+				if b[line.NewLine-1] != line.Text {
+					t.Fatalf("case %d: inserted line %+v has the wrong line number", i, line)
+				}
+			}
+		}
+		if strings.Join(oldSide, "\n") != strings.Join(a, "\n") || strings.Join(newSide, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("case %d: diff of %v and %v does not rebuild both sides", i, a, b)
+		}
+		if want := lcsLength(a, b); equal != want {
+			t.Fatalf("case %d: diff of %v and %v keeps %d lines, want %d", i, a, b, equal, want)
+		}
+	}
+}
+
+func TestDiffLinesLargeInput(t *testing.T) {
+	a := make([]string, 5000)
+	b := make([]string, 5000)
+	for i := range a {
+		a[i] = fmt.Sprintf("old %d", i)
+		b[i] = fmt.Sprintf("new %d", i)
+	}
+	if got := len(diffLines(a, b)); got != 10000 {
+		t.Errorf("diff has %d lines, want 10000", got)
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/repositories"
+	"github.com/chmenegatti/myBlog/internal/tracing"
+)
+
+// ErrFeedNotFound is returned when a category or tag feed is requested for an unknown slug
+var ErrFeedNotFound = errors.New("feed not found")
+
+// FeedScope selects which posts a feed contains. An empty scope is the main feed.
+type FeedScope struct {
+	CategorySlug string
+	TagSlug      string
+}
+
+// Feed is a format-independent syndication feed
+type Feed struct {
+	Title       string
+	Description string
+	Link        string // HTML page the feed describes
+	Updated     time.Time
+	Posts       []*models.Post
+}
+
+// Feed Service
+type FeedService interface {
+	GetFeed(ctx context.Context, scope FeedScope) (*Feed, error)
+	RSS(feed *Feed, selfURL string) ([]byte, error)
+	Atom(feed *Feed, selfURL string) ([]byte, error)
+	JSON(feed *Feed, selfURL string) ([]byte, error)
+}
+
+type feedService struct {
+	postService  PostService
+	categoryRepo repositories.CategoryRepository
+	tagRepo      repositories.TagRepository
+	site         config.SiteConfig
+	urls         SiteURLs
+}
+
+func NewFeedService(postService PostService, categoryRepo repositories.CategoryRepository, tagRepo repositories.TagRepository, site config.SiteConfig) FeedService {
+	return &feedService{
+		postService:  postService,
+		categoryRepo: categoryRepo,
+		tagRepo:      tagRepo,
+		site:         site,
+		urls:         NewSiteURLs(site.URL),
+	}
+}
+
+func (s *feedService) GetFeed(ctx context.Context, scope FeedScope) (*Feed, error) {
+	ctx, span := tracing.Start(ctx, "FeedService.GetFeed")
+	defer span.End()
+
+	feed := &Feed{
+		Title:       s.site.Title,
+		Description: s.site.Description,
+		Link:        s.urls.Home(),
+	}
+
+	var filter PostFilter
+	switch {
+	case scope.CategorySlug != "":
+		category, err := s.categoryRepo.GetBySlug(scope.CategorySlug)
+		if err != nil {
+			return nil, ErrFeedNotFound
+		}
+		filter.CategorySlug = category.Slug
+		feed.Title = s.site.Title + " - " + category.Name
+		if category.Description != "" {
+			feed.Description = category.Description
+		}
+		feed.Link = s.urls.Category(category.Slug)
+	case scope.TagSlug != "":
+		tag, err := s.tagRepo.GetBySlug(scope.TagSlug)
+		if err != nil {
+			return nil, ErrFeedNotFound
+		}
+		filter.TagSlugs = []string{tag.Slug}
+		feed.Title = s.site.Title + " - #" + tag.Name
+		feed.Link = s.urls.Tag(tag.Slug)
+	}
+
+	posts, _, err := s.postService.GetPublished(ctx, filter, s.site.FeedSize, 0)
+	if err != nil {
+		return nil, err
+	}
+	feed.Posts = posts
+
+	for _, post := range posts {
+		if post.UpdatedAt.After(feed.Updated) {
+			feed.Updated = post.UpdatedAt
+		}
+	}
+	// An empty feed needs a fixed date, so its body and ETag stay the same
+	// until a post is published
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Unix(0, 0).UTC()
+	}
+
+	return feed, nil
+}
+
+// RSS 2.0
+
+type rssDocument struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title          string        `xml:"title"`
+	Link           string        `xml:"link"`
+	GUID           rssGUID       `xml:"guid"`
+	PubDate        string        `xml:"pubDate,omitempty"`
+	Creator        string        `xml:"dc:creator,omitempty"`
+	Categories     []string      `xml:"category"`
+	Description    string        `xml:"description"`
+	ContentEncoded rssCDATA      `xml:"content:encoded"`
+	Enclosure      *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+func (s *feedService) RSS(feed *Feed, selfURL string) ([]byte, error) {
+	doc := rssDocument{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.Link,
+			Description:   feed.Description,
+			Language:      s.site.Language,
+			LastBuildDate: feed.Updated.UTC().Format(time.RFC1123Z),
+			AtomLink:      atomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+			Items:         make([]rssItem, 0, len(feed.Posts)),
+		},
+	}
+
+	for _, post := range feed.Posts {
+		link := s.urls.Post(post.Slug)
+		item := rssItem{
+			Title:          post.Title,
+			Link:           link,
+			GUID:           rssGUID{IsPermaLink: false, Value: "urn:uuid:" + post.ID.String()},
+			PubDate:        publishedTime(post).UTC().Format(time.RFC1123Z),
+			Creator:        post.Author.Name,
+			Description:    post.Excerpt,
+			ContentEncoded: rssCDATA{Value: post.ContentHTML},
+		}
+		for _, category := range post.Categories {
+			item.Categories = append(item.Categories, category.Name)
+		}
+		if image := s.featuredImage(post); image != "" {
+			item.Enclosure = &rssEnclosure{URL: image, Length: 0, Type: imageMimeType(image)}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	return marshalXML(doc)
+}
+
+// Atom 1.0
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (s *feedService) Atom(feed *Feed, selfURL string) ([]byte, error) {
+	doc := atomFeed{
+		Lang:     s.site.Language,
+		ID:       selfURL,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		Updated:  feed.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.Link, Rel: "alternate", Type: "text/html"},
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+		},
+		Entries: make([]atomEntry, 0, len(feed.Posts)),
+	}
+
+	for _, post := range feed.Posts {
+		entry := atomEntry{
+			ID:        "urn:uuid:" + post.ID.String(),
+			Title:     post.Title,
+			Updated:   post.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: publishedTime(post).UTC().Format(time.RFC3339),
+			Links:     []atomLink{{Href: s.urls.Post(post.Slug), Rel: "alternate", Type: "text/html"}},
+			Author:    atomPerson{Name: post.Author.Name},
+			Summary:   post.Excerpt,
+			Content:   atomContent{Type: "html", Value: post.ContentHTML},
+		}
+		for _, category := range post.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category.Slug, Label: category.Name})
+		}
+		if image := s.featuredImage(post); image != "" {
+			entry.Links = append(entry.Links, atomLink{Href: image, Rel: "enclosure", Type: imageMimeType(image)})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return marshalXML(doc)
+}
+
+// JSON Feed 1.1
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Language    string         `json:"language,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name   string `json:"name"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+func (s *feedService) JSON(feed *Feed, selfURL string) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     selfURL,
+		Description: feed.Description,
+		Language:    s.site.Language,
+		Items:       make([]jsonFeedItem, 0, len(feed.Posts)),
+	}
+
+	for _, post := range feed.Posts {
+		item := jsonFeedItem{
+			ID:            post.ID.String(),
+			URL:           s.urls.Post(post.Slug),
+			Title:         post.Title,
+			ContentHTML:   post.ContentHTML,
+			Summary:       post.Excerpt,
+			Image:         s.featuredImage(post),
+			DatePublished: publishedTime(post).UTC().Format(time.RFC3339),
+			DateModified:  post.UpdatedAt.UTC().Format(time.RFC3339),
+			Authors: []jsonFeedAuthor{{
+				Name:   post.Author.Name,
+				Avatar: absoluteURL(s.site.APIURL, post.Author.Avatar),
+			}},
+		}
+		for _, category := range post.Categories {
+			item.Tags = append(item.Tags, category.Name)
+		}
+		for _, tag := range post.Tags {
+			item.Tags = append(item.Tags, tag.Name)
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	return json.Marshal(doc)
+}
+
+func (s *feedService) featuredImage(post *models.Post) string {
+	return absoluteURL(s.site.APIURL, post.FeaturedImg)
+}
+
+// publishedTime returns when a post went live, falling back to its creation
+// time for posts published before publication dates were recorded
+func publishedTime(post *models.Post) time.Time {
+	if post.PublishedAt != nil {
+		return *post.PublishedAt
+	}
+	return post.CreatedAt
+}
+
+func imageMimeType(imageURL string) string {
+	if mimeType := mime.TypeByExtension(path.Ext(imageURL)); mimeType != "" {
+		return mimeType
+	}
+	return "image/jpeg"
+}
+
+func marshalXML(doc any) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/database"
+)
+
+// healthCheckTimeout bounds each readiness check, so a hung database cannot
+// hang the probe
+const healthCheckTimeout = 2 * time.Second
+
+// Health check statuses
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthCheck is the result of checking one dependency
+type HealthCheck struct {
+	Status     string         `json:"status"`
+	DurationMS int64          `json:"duration_ms"`
+	Error      string         `json:"error,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+// HealthReport is ready when every check passed
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+type HealthService interface {
+	Ready(ctx context.Context) HealthReport
+}
+
+type healthService struct {
+	db         *sql.DB
+	migrator   *database.Migrator
+	uploadPath string
+}
+
+func NewHealthService(db *sql.DB, migrator *database.Migrator, uploadPath string) HealthService {
+	return &healthService{db: db, migrator: migrator, uploadPath: uploadPath}
+}
+
+// Ready checks the database, the upload directory and that every migration
+// of this build is applied
+func (s *healthService) Ready(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Status: HealthOK,
+		Checks: map[string]HealthCheck{
+			"database":   runHealthCheck(ctx, s.checkDatabase),
+			"uploads":    runHealthCheck(ctx, s.checkUploads),
+			"migrations": runHealthCheck(ctx, s.checkMigrations),
+		},
+	}
+	for _, check := range report.Checks {
+		if check.Status != HealthOK {
+			report.Status = HealthUnavailable
+		}
+	}
+	return report
+}
+
+func runHealthCheck(ctx context.Context, check func(ctx context.Context) (map[string]any, error)) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := check(ctx)
+	result := HealthCheck{
+		Status:     HealthOK,
+		DurationMS: time.Since(start).Milliseconds(),
+		Details:    details,
+	}
+	if err != nil {
+		result.Status = HealthUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (s *healthService) checkDatabase(ctx context.Context) (map[string]any, error) {
+	stats := s.db.Stats()
+	details := map[string]any{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"max_open":         stats.MaxOpenConnections,
+		"wait_count":       stats.WaitCount,
+		"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+	}
+	return details, s.db.PingContext(ctx)
+}
+
+func (s *healthService) checkUploads(ctx context.Context) (map[string]any, error) {
+	file, err := os.CreateTemp(s.uploadPath, ".health-*")
+	if err != nil {
+		return nil, fmt.Errorf("upload directory is not writable: %w", err)
+	}
+	file.Close()
+	return nil, os.Remove(file.Name())
+}
+
+func (s *healthService) checkMigrations(ctx context.Context) (map[string]any, error) {
+	pending, err := s.migrator.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	details := map[string]any{"pending": pending}
+	if pending > 0 {
+		return details, fmt.Errorf("%d migrations are not applied", pending)
+	}
+	return details, nil
+}
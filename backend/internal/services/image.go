@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chmenegatti/myBlog/internal/metrics"
 	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/chmenegatti/myBlog/internal/repositories"
 	"github.com/google/uuid"
@@ -24,7 +25,7 @@ type ImageService interface {
 	GetImage(id uuid.UUID) (*models.Image, error)
 	GetUserImages(userID uuid.UUID, limit, offset int) ([]*models.Image, error)
 	GetAllImages(limit, offset int) ([]*models.Image, error)
-	DeleteImage(id uuid.UUID, userID uuid.UUID) error
+	DeleteImage(id uuid.UUID, actor Actor) error
 	ResizeImage(imagePath string, width, height uint) error
 }
 
@@ -118,6 +119,8 @@ func (s *imageService) UploadImage(file *multipart.FileHeader, userID uuid.UUID,
 		return nil, err
 	}
 
+	metrics.Uploads.WithLabelValues(string(category)).Inc()
+	metrics.UploadBytes.WithLabelValues(string(category)).Add(float64(file.Size))
 	return imageRecord, nil
 }
 
@@ -133,16 +136,16 @@ func (s *imageService) GetAllImages(limit, offset int) ([]*models.Image, error)
 	return s.imageRepo.GetAll(limit, offset)
 }
 
-func (s *imageService) DeleteImage(id uuid.UUID, userID uuid.UUID) error {
+func (s *imageService) DeleteImage(id uuid.UUID, actor Actor) error {
 	// Get image to verify ownership
 	image, err := s.imageRepo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
-	// Check if user owns the image (or is admin - this check should be done in handler)
-	if image.UploadedBy != userID {
-		return errors.New("unauthorized to delete this image")
+	// Only the uploader and users who manage images may delete it
+	if image.UploadedBy != actor.UserID && !actor.Can(models.PermManageImages) {
+		return ErrForbidden
 	}
 
 	// Delete from database (soft delete)
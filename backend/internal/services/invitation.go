@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/mailer"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// ErrInvitationNotFound is returned when revoking an unknown invitation
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+type InvitationService interface {
+	Create(invitedBy uuid.UUID, req *CreateInvitationRequest) (*models.Invitation, string, error)
+	List(limit, offset int) ([]*models.Invitation, int64, error)
+	Revoke(id uuid.UUID) error
+}
+
+type CreateInvitationRequest struct {
+	Email          string          `json:"email" binding:"omitempty,email"` // optional, emailed and required at registration
+	Role           models.UserRole `json:"role"`                            // defaults to contributor
+	ExpiresInHours int             `json:"expires_in_hours" binding:"min=0,max=8760"`
+}
+
+type invitationService struct {
+	invitationRepo repositories.InvitationRepository
+	mailer         mailer.Mailer
+	authCfg        config.AuthConfig
+	siteTitle      string
+	urls           SiteURLs
+}
+
+func NewInvitationService(invitationRepo repositories.InvitationRepository, mailer mailer.Mailer, authCfg config.AuthConfig, site config.SiteConfig) InvitationService {
+	return &invitationService{
+		invitationRepo: invitationRepo,
+		mailer:         mailer,
+		authCfg:        authCfg,
+		siteTitle:      site.Title,
+		urls:           NewSiteURLs(site.URL),
+	}
+}
+
+// Create issues an invitation and returns its registration link, which is
+// only known at this point. Invitations for an email address are also sent
+// there.
+func (s *invitationService) Create(invitedBy uuid.UUID, req *CreateInvitationRequest) (*models.Invitation, string, error) {
+	role := req.Role
+	if role == "" {
+		role = models.RoleContributor
+	}
+	if !role.Valid() {
+		return nil, "", ErrInvalidRole
+	}
+
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = s.authCfg.InvitationExpiration
+	}
+
+	rawToken, tokenHash, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := &models.Invitation{
+		Email:       req.Email,
+		Role:        role,
+		TokenHash:   tokenHash,
+		InvitedByID: invitedBy,
+		ExpiresAt:   time.Now().Add(time.Duration(hours) * time.Hour),
+	}
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, "", err
+	}
+
+	link := s.urls.Invitation(rawToken)
+	if invitation.Email != "" {
+		msg := mailer.Message{
+			To:      invitation.Email,
+			Subject: fmt.Sprintf("You are invited to %s", s.siteTitle),
+			Body: fmt.Sprintf("Hi,\n\n"+
+				"You have been invited to join %s as %s. Open the link below to create your account:\n\n"+
+				"%s\n\n"+
+				"The invitation expires on %s.\n",
+				s.siteTitle, role, link, invitation.ExpiresAt.Format("January 2, 2006 15:04 MST")),
+		}
+		if err := s.mailer.Send(msg); err != nil {
+			logger.WithError(err).Error("Failed to send invitation email", map[string]any{
+				"invitation_id": invitation.ID.String(),
+			})
+		}
+	}
+
+	return invitation, link, nil
+}
+
+func (s *invitationService) List(limit, offset int) ([]*models.Invitation, int64, error) {
+	return s.invitationRepo.List(limit, offset)
+}
+
+func (s *invitationService) Revoke(id uuid.UUID) error {
+	deleted, err := s.invitationRepo.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
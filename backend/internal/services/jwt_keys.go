@@ -0,0 +1,276 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing tokens
+const minRSAKeyBits = 2048
+
+// KeySet signs and verifies JWTs. Every token names the key that signed it in
+// its "kid" header, so after a rotation the previous keys keep verifying the
+// tokens they signed until those expire.
+type KeySet struct {
+	issuer  string
+	signing *jwtKey
+	keys    map[string]*jwtKey // by kid, including the signing key
+}
+
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   any // private key or HMAC secret, nil for keys that only verify
+	verify any // public key or HMAC secret
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet loads the signing key for the configured algorithm together with
+// the keys of previous rotations
+func NewKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	var signing *jwtKey
+	switch strings.ToUpper(cfg.Algorithm) {
+	case "", "HS256":
+		if cfg.Secret == "" {
+			return nil, errors.New("JWT_SECRET is required for HS256")
+		}
+		signing = hmacKey(cfg.Secret)
+	case "RS256", "EDDSA":
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", cfg.Algorithm)
+		}
+		key, err := loadKeyFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if key.sign == nil {
+			return nil, fmt.Errorf("%s does not contain a private key", cfg.PrivateKeyFile)
+		}
+		if !strings.EqualFold(key.method.Alg(), cfg.Algorithm) {
+			return nil, fmt.Errorf("%s holds a %s key, not %s", cfg.PrivateKeyFile, key.method.Alg(), cfg.Algorithm)
+		}
+		signing = key
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	keys := &KeySet{
+		issuer:  cfg.Issuer,
+		signing: signing,
+		keys:    map[string]*jwtKey{signing.id: signing},
+	}
+
+	// Previous keys only verify, even when a private key file is given
+	for _, path := range cfg.PublicKeyFiles {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := keys.keys[key.id]; !exists {
+			key.sign = nil
+			keys.keys[key.id] = key
+		}
+	}
+	for _, secret := range cfg.PreviousSecrets {
+		key := hmacKey(secret)
+		if _, exists := keys.keys[key.id]; !exists {
+			key.sign = nil
+			keys.keys[key.id] = key
+		}
+	}
+
+	return keys, nil
+}
+
+// Sign signs claims with the current signing key
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+	return token.SignedString(k.signing.sign)
+}
+
+// parse verifies a token against the key named by its kid. The algorithm must
+// be the key's own, so a public key can never be used as an HMAC secret, and
+// the token must come from this issuer.
+func (k *KeySet) parse(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	opts = append(opts, jwt.WithIssuer(k.issuer))
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.verify, nil
+	}, opts...)
+}
+
+// JWKS returns the public verification keys, signing key first. HMAC secrets
+// are never published, so with HS256 the set is empty.
+func (k *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	if jwk, ok := k.signing.jwk(); ok {
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		if id != k.signing.id {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if jwk, ok := k.keys[id].jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func (key *jwtKey) jwk() (JWK, bool) {
+	switch pub := key.verify.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: key.id,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: key.id,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	}
+	return JWK{}, false
+}
+
+func hmacKey(secret string) *jwtKey {
+	sum := sha256.Sum256([]byte("hs256:" + secret))
+	return &jwtKey{
+		id:     "hs-" + base64.RawURLEncoding.EncodeToString(sum[:8]),
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// loadKeyFile reads a PEM encoded RSA or Ed25519 key. Private keys may be
+// PKCS#8 or PKCS#1, public keys PKIX or PKCS#1.
+func loadKeyFile(path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading JWT key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var private crypto.Signer
+	var public crypto.PublicKey
+	if strings.Contains(block.Type, "PRIVATE KEY") {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key %s: %w", path, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported private key type %T", path, parsed)
+		}
+		private, public = signer, signer.Public()
+	} else {
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key %s: %w", path, err)
+		}
+	}
+
+	key := &jwtKey{verify: public}
+	if private != nil {
+		key.sign = private
+	}
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%s: RSA keys must have at least %d bits", path, minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T, use RSA or Ed25519", path, public)
+	}
+
+	jwk, _ := key.jwk()
+	key.id = thumbprint(jwk)
+	return key, nil
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint, used as the key ID
+func thumbprint(jwk JWK) string {
+	var members any
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
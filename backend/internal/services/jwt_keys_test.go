@@ -0,0 +1,304 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testIssuer = "https://blog.example.com"
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeEd25519Key writes a new Ed25519 key pair and returns the paths of the
+// private and public key files
+func writeEd25519Key(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name+".pem", "PRIVATE KEY", privDER),
+		writePEM(t, dir, name+".pub.pem", "PUBLIC KEY", pubDER)
+}
+
+func newTestKeySet(t *testing.T, cfg config.JWTConfig) *KeySet {
+	t.Helper()
+	if cfg.Issuer == "" {
+		cfg.Issuer = testIssuer
+	}
+	keys, err := NewKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func sessionToken(t *testing.T, keys *KeySet) string {
+	t.Helper()
+	s := &authService{keys: keys, jwtCfg: config.JWTConfig{AccessExpiration: 15}}
+	token, err := s.generateToken(&models.User{ID: uuid.New(), Role: models.RoleAuthor}, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	dir := t.TempDir()
+	edPriv, edPub := writeEd25519Key(t, dir, "ed")
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallPath := writePEM(t, dir, "small.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(small))
+	notPEM := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.JWTConfig
+		wantErr string
+	}{
+		{"HS256 without secret", config.JWTConfig{Algorithm: "HS256"}, "JWT_SECRET is required"},
+		{"unsupported algorithm", config.JWTConfig{Algorithm: "ES256", Secret: "s"}, "unsupported JWT algorithm"},
+		{"RS256 without key file", config.JWTConfig{Algorithm: "RS256"}, "JWT_PRIVATE_KEY_FILE is required"},
+		{"missing key file", config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: filepath.Join(dir, "missing.pem")}, "reading JWT key"},
+		{"not PEM", config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: notPEM}, "not a PEM file"},
+		{"public key as signing key", config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: edPub}, "does not contain a private key"},
+		{"algorithm mismatch", config.JWTConfig{Algorithm: "RS256", PrivateKeyFile: edPriv}, "holds a EdDSA key"},
+		{"small RSA key", config.JWTConfig{Algorithm: "RS256", PrivateKeyFile: smallPath}, "at least 2048 bits"},
+		{"bad previous key", config.JWTConfig{Algorithm: "HS256", Secret: "s", PublicKeyFiles: []string{notPEM}}, "not a PEM file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeySet(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldPriv, oldPub := writeEd25519Key(t, dir, "old")
+	newPriv, _ := writeEd25519Key(t, dir, "new")
+
+	tests := []struct {
+		name      string
+		old, next config.JWTConfig
+		wantValid bool
+	}{
+		{
+			"HS256 previous secret verifies",
+			config.JWTConfig{Algorithm: "HS256", Secret: "old-secret"},
+			config.JWTConfig{Algorithm: "HS256", Secret: "new-secret", PreviousSecrets: []string{"old-secret"}},
+			true,
+		},
+		{
+			"HS256 retired secret is rejected",
+			config.JWTConfig{Algorithm: "HS256", Secret: "old-secret"},
+			config.JWTConfig{Algorithm: "HS256", Secret: "new-secret"},
+			false,
+		},
+		{
+			"EdDSA previous public key verifies",
+			config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: oldPriv},
+			config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: newPriv, PublicKeyFiles: []string{oldPub}},
+			true,
+		},
+		{
+			"EdDSA previous private key file verifies",
+			config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: oldPriv},
+			config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: newPriv, PublicKeyFiles: []string{oldPriv}},
+			true,
+		},
+		{
+			"EdDSA retired key is rejected",
+			config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: oldPriv},
+			config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: newPriv},
+			false,
+		},
+		{
+			"HS256 to EdDSA keeps the secret for verifying",
+			config.JWTConfig{Algorithm: "HS256", Secret: "old-secret"},
+			config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: newPriv, PreviousSecrets: []string{"old-secret"}},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldKeys := newTestKeySet(t, tt.old)
+			newKeys := newTestKeySet(t, tt.next)
+
+			_, err := ValidateJWT(sessionToken(t, oldKeys), newKeys)
+			if valid := err == nil; valid != tt.wantValid {
+				t.Errorf("old token valid = %v (%v), want %v", valid, err, tt.wantValid)
+			}
+			if _, err := ValidateJWT(sessionToken(t, newKeys), newKeys); err != nil {
+				t.Errorf("new token rejected: %v", err)
+			}
+			if _, err := ValidateJWT(sessionToken(t, newKeys), oldKeys); err == nil {
+				t.Error("old key set accepted a token signed with the new key")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	edPriv, _ := writeEd25519Key(t, dir, "ed")
+	_, prevPub := writeEd25519Key(t, dir, "prev")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPriv := writePEM(t, dir, "rsa.pem", "PRIVATE KEY", rsaDER)
+
+	tests := []struct {
+		name     string
+		cfg      config.JWTConfig
+		wantKeys []string // kty of each published key, signing key first
+	}{
+		{"HS256 publishes nothing", config.JWTConfig{Algorithm: "HS256", Secret: "s", PreviousSecrets: []string{"old"}}, nil},
+		{"EdDSA", config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: edPriv}, []string{"OKP"}},
+		{"RS256 with previous key", config.JWTConfig{Algorithm: "RS256", PrivateKeyFile: rsaPriv, PublicKeyFiles: []string{prevPub}}, []string{"RSA", "OKP"}},
+		{"previous secrets stay private", config.JWTConfig{Algorithm: "EdDSA", PrivateKeyFile: edPriv, PreviousSecrets: []string{"old"}}, []string{"OKP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := newTestKeySet(t, tt.cfg)
+			set := keys.JWKS()
+			if len(set.Keys) != len(tt.wantKeys) {
+				t.Fatalf("published %d keys, want %d", len(set.Keys), len(tt.wantKeys))
+			}
+			for i, jwk := range set.Keys {
+				if jwk.Kty != tt.wantKeys[i] || jwk.Use != "sig" || jwk.Kid == "" {
+					t.Errorf("key %d = %+v, want a %s signing key", i, jwk, tt.wantKeys[i])
+				}
+				if jwk.Kid != thumbprint(jwk) {
+					t.Errorf("key %d kid %q is not its thumbprint", i, jwk.Kid)
+				}
+			}
+			if len(set.Keys) == 0 {
+				return
+			}
+
+			// The signing key comes first and names the kid tokens carry
+			token, _, err := jwt.NewParser().ParseUnverified(sessionToken(t, keys), &JWTClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Header["kid"] != set.Keys[0].Kid || token.Method.Alg() != set.Keys[0].Alg {
+				t.Errorf("token header %v does not match the first published key %+v", token.Header, set.Keys[0])
+			}
+		})
+	}
+
+	rsaSet := newTestKeySet(t, config.JWTConfig{Algorithm: "RS256", PrivateKeyFile: rsaPriv}).JWKS()
+	if rsaSet.Keys[0].E != "AQAB" || rsaSet.Keys[0].N == "" {
+		t.Errorf("RSA key = %+v, want modulus and exponent AQAB", rsaSet.Keys[0])
+	}
+}
+
+func TestTokenAudiences(t *testing.T) {
+	keys := newTestKeySet(t, config.JWTConfig{Algorithm: "HS256", Secret: "secret"})
+	s := &authService{keys: keys, jwtCfg: config.JWTConfig{AccessExpiration: 15}}
+	user := &models.User{ID: uuid.New(), Role: models.RoleAuthor}
+
+	challenge := func(audience string) string {
+		t.Helper()
+		resp, err := s.challengeResponse(user, audience)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.ChallengeToken
+	}
+	signed := func(keys *KeySet, claims jwt.RegisteredClaims) string {
+		t.Helper()
+		token, err := keys.Sign(JWTClaims{UserID: user.ID, Role: user.Role, RegisteredClaims: claims})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	session, err := s.generateToken(user, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inAnHour := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	otherIssuer := newTestKeySet(t, config.JWTConfig{Algorithm: "HS256", Secret: "secret", Issuer: "https://other.example.com"})
+
+	tests := []struct {
+		name     string
+		token    string
+		validate func(string, *KeySet) (*JWTClaims, error)
+		wantOK   bool
+	}{
+		{"session token as session", session, ValidateJWT, true},
+		{"login challenge as session", challenge(audienceTwoFactorLogin), ValidateJWT, false},
+		{"enrollment challenge as session", challenge(audienceTwoFactorEnroll), ValidateJWT, false},
+		{"no audience", signed(keys, jwt.RegisteredClaims{Issuer: testIssuer, ExpiresAt: inAnHour}), ValidateJWT, false},
+		{"other issuer", signed(otherIssuer, jwt.RegisteredClaims{Issuer: "https://other.example.com", Audience: jwt.ClaimStrings{AudienceSession}, ExpiresAt: inAnHour}), ValidateJWT, false},
+		{"expired", signed(keys, jwt.RegisteredClaims{Issuer: testIssuer, Audience: jwt.ClaimStrings{AudienceSession}, ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}), ValidateJWT, false},
+		{"enrollment challenge", challenge(audienceTwoFactorEnroll), ValidateEnrollmentJWT, true},
+		{"login challenge as enrollment", challenge(audienceTwoFactorLogin), ValidateEnrollmentJWT, false},
+		{"session token as enrollment", session, ValidateEnrollmentJWT, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.validate(tt.token, keys)
+			if ok := err == nil; ok != tt.wantOK {
+				t.Fatalf("valid = %v (%v), want %v", ok, err, tt.wantOK)
+			}
+			if tt.wantOK && claims.UserID != user.ID {
+				t.Errorf("user = %s, want %s", claims.UserID, user.ID)
+			}
+		})
+	}
+
+	// A login challenge only passes the check for its own audience
+	if _, err := validateChallengeJWT(challenge(audienceTwoFactorLogin), keys, audienceTwoFactorLogin); err != nil {
+		t.Errorf("login challenge rejected: %v", err)
+	}
+	if _, err := validateChallengeJWT(session, keys, audienceTwoFactorLogin); err == nil {
+		t.Error("session token accepted as a login challenge")
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/google/uuid"
+)
+
+// LoginLockedError is returned while an account or client IP is locked out
+// after too many failed logins
+type LoginLockedError struct {
+	RetryAfter time.Duration
+	Scope      string // "account" or "ip"
+	JustLocked bool   // the failed attempt being reported caused the lockout
+}
+
+func (e *LoginLockedError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
+// lockoutPolicy decides how long to lock out after consecutive failures: not
+// at all below maxAttempts, then first, doubling with every further failure
+// up to max
+type lockoutPolicy struct {
+	maxAttempts int
+	first       time.Duration
+	max         time.Duration
+}
+
+func newLockoutPolicy(maxAttempts int, cfg config.AuthConfig) lockoutPolicy {
+	return lockoutPolicy{
+		maxAttempts: maxAttempts,
+		first:       time.Duration(cfg.LoginLockout) * time.Second,
+		max:         time.Duration(cfg.LoginMaxLockout) * time.Second,
+	}
+}
+
+func (p lockoutPolicy) duration(failures int) time.Duration {
+	if p.maxAttempts <= 0 || failures < p.maxAttempts {
+		return 0
+	}
+	lockout := p.first
+	for i := p.maxAttempts; i < failures && lockout < p.max; i++ {
+		lockout *= 2
+	}
+	return min(lockout, p.max)
+}
+
+// attemptTracker counts failed logins per client IP or per email without an
+// account. It lives in memory, so every instance of the API keeps its own counts.
+type attemptTracker struct {
+	policy    lockoutPolicy
+	mu        sync.Mutex
+	entries   map[string]*failedAttempts
+	lastSweep time.Time
+}
+
+type failedAttempts struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+func newAttemptTracker(policy lockoutPolicy) *attemptTracker {
+	return &attemptTracker{
+		policy:  policy,
+		entries: make(map[string]*failedAttempts),
+	}
+}
+
+// lockedFor returns how long key stays locked out
+func (t *attemptTracker) lockedFor(key string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.entries[key]; ok && entry.lockedUntil.After(now) {
+		return entry.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// fail records a failed login for key and returns the lockout it caused, if any
+func (t *attemptTracker) fail(key string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep(now)
+
+	entry, ok := t.entries[key]
+	if !ok || now.Sub(entry.last) > t.policy.max {
+		entry = &failedAttempts{}
+		t.entries[key] = entry
+	}
+	entry.count++
+	entry.last = now
+
+	lockout := t.policy.duration(entry.count)
+	if lockout > 0 {
+		entry.lockedUntil = now.Add(lockout)
+	}
+	return lockout
+}
+
+// sweep drops entries whose failures are old enough to be forgotten
+func (t *attemptTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.policy.max {
+		return
+	}
+	t.lastSweep = now
+	for key, entry := range t.entries {
+		if now.Sub(entry.last) > t.policy.max && !entry.lockedUntil.After(now) {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// checkLoginAllowed refuses logins from a locked out client IP or account.
+// The account lock is checked before the password so that guessing stops
+// while it lasts.
+func (s *authService) checkLoginAllowed(user *models.User, client ClientInfo, now time.Time) error {
+	if lockout := s.ipAttempts.lockedFor(client.IPAddress, now); lockout > 0 {
+		return &LoginLockedError{RetryAfter: lockout, Scope: "ip"}
+	}
+	if user != nil && user.LockedUntil != nil && user.LockedUntil.After(now) {
+		return &LoginLockedError{RetryAfter: user.LockedUntil.Sub(now), Scope: "account"}
+	}
+	return nil
+}
+
+// loginFailed records a failed password or second factor for the client IP
+// and, when known, the account. It returns the lockout this failure caused,
+// or err otherwise.
+func (s *authService) loginFailed(user *models.User, client ClientInfo, err error) error {
+	now := time.Now()
+
+	if user != nil {
+		failures, recordErr := s.userRepo.RecordLoginFailure(user.ID, now, now.Add(-s.accountPolicy.max))
+		if recordErr != nil {
+			logger.WithError(recordErr).Error("Failed to record failed login", map[string]any{
+				"user_id": user.ID.String(),
+			})
+		} else if lockout := s.accountPolicy.duration(failures); lockout > 0 {
+			if lockErr := s.userRepo.LockUntil(user.ID, now.Add(lockout)); lockErr != nil {
+				logger.WithError(lockErr).Error("Failed to lock account", map[string]any{
+					"user_id": user.ID.String(),
+				})
+			}
+			s.ipAttempts.fail(client.IPAddress, now)
+			return &LoginLockedError{RetryAfter: lockout, Scope: "account", JustLocked: true}
+		}
+	}
+
+	if lockout := s.ipAttempts.fail(client.IPAddress, now); lockout > 0 {
+		return &LoginLockedError{RetryAfter: lockout, Scope: "ip", JustLocked: true}
+	}
+	return err
+}
+
+// unknownAccountLoginFailed handles a login for an email without an account.
+// Its failures are counted and locked out like an account's, so that the
+// responses never reveal whether an account exists.
+func (s *authService) unknownAccountLoginFailed(email string, client ClientInfo, now time.Time, err error) error {
+	key := strings.ToLower(strings.TrimSpace(email))
+	if lockout := s.unknownAttempts.lockedFor(key, now); lockout > 0 {
+		return &LoginLockedError{RetryAfter: lockout, Scope: "account"}
+	}
+	if lockout := s.unknownAttempts.fail(key, now); lockout > 0 {
+		s.ipAttempts.fail(client.IPAddress, now)
+		return &LoginLockedError{RetryAfter: lockout, Scope: "account", JustLocked: true}
+	}
+	return s.loginFailed(nil, client, err)
+}
+
+// UnlockAccount lifts a lockout and resets the account's failed logins
+func (s *authService) UnlockAccount(userID uuid.UUID) error {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return err
+	}
+	return s.userRepo.ClearLoginFailures(userID)
+}
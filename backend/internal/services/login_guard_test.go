@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyDuration(t *testing.T) {
+	policy := lockoutPolicy{maxAttempts: 5, first: time.Minute, max: 10 * time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{8, 8 * time.Minute},
+		{9, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.duration(tt.failures); got != tt.want {
+			t.Errorf("duration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	disabled := lockoutPolicy{maxAttempts: 0, first: time.Minute, max: time.Hour}
+	if got := disabled.duration(100); got != 0 {
+		t.Errorf("disabled policy locked out for %v", got)
+	}
+}
+
+func TestAttemptTracker(t *testing.T) {
+	policy := lockoutPolicy{maxAttempts: 3, first: time.Minute, max: 10 * time.Minute}
+	start := time.Unix(1_000_000, 0)
+
+	tests := []struct {
+		name       string
+		after      []time.Duration // offsets from start of each failure
+		wantLock   time.Duration   // lockout caused by the last failure
+		checkAfter time.Duration   // offset at which lockedFor is checked
+		wantLocked time.Duration
+	}{
+		{"below threshold", []time.Duration{0, time.Second}, 0, 2 * time.Second, 0},
+		{"locks at threshold", []time.Duration{0, time.Second, 2 * time.Second}, time.Minute, 2 * time.Second, time.Minute},
+		{"lock expires", []time.Duration{0, time.Second, 2 * time.Second}, time.Minute, 2*time.Second + time.Minute, 0},
+		{"doubles", []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second}, 2 * time.Minute, 3 * time.Second, 2 * time.Minute},
+		{"old failures are forgotten", []time.Duration{0, time.Second, 11 * time.Minute}, 0, 11 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newAttemptTracker(policy)
+			var lockout time.Duration
+			for _, offset := range tt.after {
+				lockout = tracker.fail("203.0.113.7", start.Add(offset))
+			}
+			if lockout != tt.wantLock {
+				t.Errorf("fail locked out for %v, want %v", lockout, tt.wantLock)
+			}
+			if got := tracker.lockedFor("203.0.113.7", start.Add(tt.checkAfter)); got != tt.wantLocked {
+				t.Errorf("lockedFor = %v, want %v", got, tt.wantLocked)
+			}
+			if got := tracker.lockedFor("198.51.100.1", start.Add(tt.checkAfter)); got != 0 {
+				t.Errorf("other key locked for %v", got)
+			}
+		})
+	}
+}
+
+func TestAttemptTrackerSweep(t *testing.T) {
+	policy := lockoutPolicy{maxAttempts: 3, first: time.Minute, max: 10 * time.Minute}
+	tracker := newAttemptTracker(policy)
+	start := time.Unix(1_000_000, 0)
+
+	tracker.fail("stale", start)
+	tracker.fail("recent", start.Add(5*time.Minute))
+	tracker.fail("trigger", start.Add(11*time.Minute))
+
+	if _, ok := tracker.entries["stale"]; ok {
+		t.Error("stale entry was not swept")
+	}
+	if _, ok := tracker.entries["recent"]; !ok {
+		t.Error("recent entry was swept")
+	}
+}
+
+func TestUnknownAccountLoginFailed(t *testing.T) {
+	policy := lockoutPolicy{maxAttempts: 2, first: time.Minute, max: 10 * time.Minute}
+	s := &authService{
+		ipAttempts:      newAttemptTracker(lockoutPolicy{maxAttempts: 100, first: time.Minute, max: 10 * time.Minute}),
+		unknownAttempts: newAttemptTracker(policy),
+	}
+	client := ClientInfo{IPAddress: "203.0.113.7"}
+	invalid := errors.New("invalid credentials")
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		email      string
+		wantLocked bool
+		justLocked bool
+	}{
+		{"first failure", "ghost@example.com", false, false},
+		{"counted case-insensitively", " Ghost@Example.com ", true, true},
+		{"stays locked", "ghost@example.com", true, false},
+		{"other email", "nobody@example.com", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.unknownAccountLoginFailed(tt.email, client, now, invalid)
+			var locked *LoginLockedError
+			if !errors.As(err, &locked) {
+				if tt.wantLocked {
+					t.Fatalf("err = %v, want a lockout", err)
+				}
+				if err != invalid {
+					t.Errorf("err = %v, want the credentials error", err)
+				}
+				return
+			}
+			if !tt.wantLocked {
+				t.Fatalf("unexpected lockout %+v", locked)
+			}
+			if locked.Scope != "account" || locked.JustLocked != tt.justLocked {
+				t.Errorf("lockout = %+v, want account scope with JustLocked %v", locked, tt.justLocked)
+			}
+		})
+	}
+}
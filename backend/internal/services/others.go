@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 
+	"github.com/chmenegatti/myBlog/internal/cache"
+	"github.com/chmenegatti/myBlog/internal/metrics"
 	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/chmenegatti/myBlog/internal/repositories"
 	"github.com/google/uuid"
@@ -21,6 +24,7 @@ type CategoryService interface {
 
 type categoryService struct {
 	categoryRepo repositories.CategoryRepository
+	cache        *cache.Cache
 }
 
 type CreateCategoryRequest struct {
@@ -29,12 +33,17 @@ type CreateCategoryRequest struct {
 	Color       string `json:"color"`
 }
 
-func NewCategoryService(categoryRepo repositories.CategoryRepository) CategoryService {
-	return &categoryService{categoryRepo: categoryRepo}
+func NewCategoryService(categoryRepo repositories.CategoryRepository, responseCache *cache.Cache) CategoryService {
+	return &categoryService{categoryRepo: categoryRepo, cache: responseCache}
 }
 
 func (s *categoryService) Create(req *CreateCategoryRequest) (*models.Category, error) {
-	slug := generateSlug(req.Name)
+	slug, err := uniqueSlug(generateSlug(req.Name), "category", func(slug string) (bool, error) {
+		return s.categoryRepo.SlugExists(slug, uuid.Nil)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	category := &models.Category{
 		Name:        req.Name,
@@ -46,6 +55,7 @@ func (s *categoryService) Create(req *CreateCategoryRequest) (*models.Category,
 	if err := s.categoryRepo.Create(category); err != nil {
 		return nil, err
 	}
+	s.cache.Invalidate(context.Background(), cache.AreaCategories)
 
 	return category, nil
 }
@@ -55,15 +65,30 @@ func (s *categoryService) GetByID(id uuid.UUID) (*models.Category, error) {
 }
 
 func (s *categoryService) Update(category *models.Category) error {
-	return s.categoryRepo.Update(category)
+	if err := s.categoryRepo.Update(category); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
 }
 
 func (s *categoryService) Delete(id uuid.UUID) error {
-	return s.categoryRepo.Delete(id)
+	if err := s.categoryRepo.Delete(id); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
 }
 
 func (s *categoryService) List() ([]*models.Category, error) {
-	return s.categoryRepo.List()
+	return cache.Fetch(context.Background(), s.cache, cache.AreaCategories, "list", func(context.Context) ([]*models.Category, error) {
+		return s.categoryRepo.List()
+	})
+}
+
+// changed drops the cached category list and the cached posts, which embed their categories
+func (s *categoryService) changed() {
+	s.cache.Invalidate(context.Background(), cache.AreaCategories, cache.AreaPosts)
 }
 
 // Tag Service
@@ -77,18 +102,24 @@ type TagService interface {
 
 type tagService struct {
 	tagRepo repositories.TagRepository
+	cache   *cache.Cache
 }
 
 type CreateTagRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
-func NewTagService(tagRepo repositories.TagRepository) TagService {
-	return &tagService{tagRepo: tagRepo}
+func NewTagService(tagRepo repositories.TagRepository, responseCache *cache.Cache) TagService {
+	return &tagService{tagRepo: tagRepo, cache: responseCache}
 }
 
 func (s *tagService) Create(req *CreateTagRequest) (*models.Tag, error) {
-	slug := generateSlug(req.Name)
+	slug, err := uniqueSlug(generateSlug(req.Name), "tag", func(slug string) (bool, error) {
+		return s.tagRepo.SlugExists(slug, uuid.Nil)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	tag := &models.Tag{
 		Name: req.Name,
@@ -98,6 +129,7 @@ func (s *tagService) Create(req *CreateTagRequest) (*models.Tag, error) {
 	if err := s.tagRepo.Create(tag); err != nil {
 		return nil, err
 	}
+	s.cache.Invalidate(context.Background(), cache.AreaTags)
 
 	return tag, nil
 }
@@ -107,15 +139,30 @@ func (s *tagService) GetByID(id uuid.UUID) (*models.Tag, error) {
 }
 
 func (s *tagService) Update(tag *models.Tag) error {
-	return s.tagRepo.Update(tag)
+	if err := s.tagRepo.Update(tag); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
 }
 
 func (s *tagService) Delete(id uuid.UUID) error {
-	return s.tagRepo.Delete(id)
+	if err := s.tagRepo.Delete(id); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
 }
 
 func (s *tagService) List() ([]*models.Tag, error) {
-	return s.tagRepo.List()
+	return cache.Fetch(context.Background(), s.cache, cache.AreaTags, "list", func(context.Context) ([]*models.Tag, error) {
+		return s.tagRepo.List()
+	})
+}
+
+// changed drops the cached tag list and the cached posts, which embed their tags
+func (s *tagService) changed() {
+	s.cache.Invalidate(context.Background(), cache.AreaTags, cache.AreaPosts)
 }
 
 // Comment Service
@@ -128,6 +175,7 @@ type CommentService interface {
 	List(limit, offset int) ([]*models.Comment, int64, error)
 	Approve(id uuid.UUID) error
 	Reject(id uuid.UUID) error
+	CountPending() (int64, error)
 }
 
 type commentService struct {
@@ -185,24 +233,17 @@ func (s *commentService) List(limit, offset int) ([]*models.Comment, int64, erro
 	return s.commentRepo.List(limit, offset)
 }
 
-func (s *commentService) Approve(id uuid.UUID) error {
-	comment, err := s.commentRepo.GetByID(id)
-	if err != nil {
-		return err
-	}
+// CountPending returns how many comments are awaiting moderation
+func (s *commentService) CountPending() (int64, error) {
+	return s.commentRepo.CountByStatus(models.CommentPending)
+}
 
-	comment.Status = models.CommentApproved
-	return s.commentRepo.Update(comment)
+func (s *commentService) Approve(id uuid.UUID) error {
+	return s.commentRepo.SetStatus(id, models.CommentApproved)
 }
 
 func (s *commentService) Reject(id uuid.UUID) error {
-	comment, err := s.commentRepo.GetByID(id)
-	if err != nil {
-		return err
-	}
-
-	comment.Status = models.CommentRejected
-	return s.commentRepo.Update(comment)
+	return s.commentRepo.SetStatus(id, models.CommentRejected)
 }
 
 // Newsletter Service
@@ -232,6 +273,7 @@ func (s *newsletterService) Subscribe(email string) (*models.Newsletter, error)
 		if err := s.newsletterRepo.Update(existing); err != nil {
 			return nil, err
 		}
+		metrics.NewsletterSubscriptions.Inc()
 		return existing, nil
 	}
 
@@ -251,6 +293,7 @@ func (s *newsletterService) Subscribe(email string) (*models.Newsletter, error)
 		return nil, err
 	}
 
+	metrics.NewsletterSubscriptions.Inc()
 	return newsletter, nil
 }
 
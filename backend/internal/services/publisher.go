@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/logger"
+)
+
+// ScheduledPublisher periodically promotes scheduled posts whose publish_at has passed.
+// It keeps no state of its own: every run reads due posts from the database, so posts
+// scheduled before a restart are picked up on the first run after it.
+type ScheduledPublisher struct {
+	postService PostService
+	interval    time.Duration
+	stop        chan struct{}
+	done        chan struct{}
+	startOnce   sync.Once
+	stopOnce    sync.Once
+	started     bool
+}
+
+// NewScheduledPublisher creates a publisher that checks for due posts every interval
+func NewScheduledPublisher(postService PostService, interval time.Duration) *ScheduledPublisher {
+	return &ScheduledPublisher{
+		postService: postService,
+		interval:    interval,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start runs the publisher in a background goroutine
+func (p *ScheduledPublisher) Start() {
+	p.startOnce.Do(func() {
+		p.started = true
+		logger.WithService("scheduled_publisher").Info("Scheduled publisher started", map[string]any{
+			"interval": p.interval.String(),
+		})
+
+		go p.run()
+	})
+}
+
+// Stop signals the publisher to exit and waits for the current run to finish
+func (p *ScheduledPublisher) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		if p.started {
+			<-p.done
+		}
+	})
+}
+
+func (p *ScheduledPublisher) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	// Catch up on anything that became due while the server was down
+	p.publishDue()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.publishDue()
+		case <-p.stop:
+			logger.WithService("scheduled_publisher").Info("Scheduled publisher stopped")
+			return
+		}
+	}
+}
+
+func (p *ScheduledPublisher) publishDue() {
+	published, err := p.postService.PublishDue(context.Background(), time.Now())
+	if err != nil {
+		logger.WithService("scheduled_publisher").Error("Failed to publish scheduled posts", map[string]any{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if published > 0 {
+		logger.WithService("scheduled_publisher").Info("Scheduled posts published", map[string]any{
+			"count": published,
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/mailer"
+	"github.com/chmenegatti/myBlog/internal/models"
+)
+
+// registrationInvitation enforces the registration mode, returning the
+// invitation the request registers with, if any. Unknown modes count as
+// closed.
+func (s *authService) registrationInvitation(req *RegisterRequest) (*models.Invitation, error) {
+	switch s.authCfg.RegistrationMode {
+	case config.RegistrationOpen:
+		if req.InvitationToken == "" {
+			return nil, nil
+		}
+	case config.RegistrationInvite:
+		if req.InvitationToken == "" {
+			return nil, ErrInvitationRequired
+		}
+	default:
+		return nil, ErrRegistrationClosed
+	}
+
+	invitation, err := s.invitationRepo.GetByHash(hashToken(req.InvitationToken))
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	if invitation.UsedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvalidInvitation
+	}
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, req.Email) {
+		return nil, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+// sendVerificationEmail replaces the user's verification link with a new one
+func (s *authService) sendVerificationEmail(user *models.User) error {
+	if err := s.verificationRepo.DeleteForUser(user.ID); err != nil {
+		return err
+	}
+
+	rawToken, tokenHash, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiration := time.Duration(s.authCfg.EmailVerificationExpiration) * time.Hour
+	if err := s.verificationRepo.Create(&models.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(expiration),
+	}); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Thanks for signing up. Open the link below to confirm your email address and activate your account:\n\n"+
+			"%s\n\n"+
+			"The link expires in %d hours. If you did not sign up, you can ignore this email.\n",
+			user.Name, s.urls.VerifyEmail(rawToken), s.authCfg.EmailVerificationExpiration),
+	}
+
+	if err := s.mailer.Send(msg); err != nil {
+		logger.WithError(err).Error("Failed to send email verification", map[string]any{
+			"user_id": user.ID.String(),
+		})
+	}
+
+	return nil
+}
+
+// VerifyEmail confirms the address of a newly registered user and activates
+// the account
+func (s *authService) VerifyEmail(token string) error {
+	stored, err := s.verificationRepo.GetByHash(hashToken(token))
+	if err != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	user.IsActive = true
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	return s.verificationRepo.DeleteForUser(user.ID)
+}
+
+// ResendVerification emails a new verification link to an account that is
+// still waiting for one. Like ForgotPassword it never reveals whether the
+// account exists.
+func (s *authService) ResendVerification(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || user.IsActive || user.EmailVerifiedAt != nil {
+		return nil
+	}
+	if pending, err := s.verificationRepo.ExistsForUser(user.ID); err != nil || !pending {
+		return err
+	}
+	return s.sendVerificationEmail(user)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/tracing"
+	"github.com/google/uuid"
+)
+
+// RevisionDiff describes the changes between two versions of a post
+type RevisionDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"` // 0 means the current version of the post
+	Fields  []FieldChange `json:"fields"`
+	Lines   []DiffLine    `json:"lines"`
+	Added   int           `json:"added"`
+	Removed int           `json:"removed"`
+}
+
+// FieldChange reports a single-value field that differs between two versions
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+func (s *postService) ListRevisions(ctx context.Context, postID uuid.UUID, actor Actor) ([]*models.PostRevision, error) {
+	ctx, span := tracing.Start(ctx, "PostService.ListRevisions")
+	defer span.End()
+
+	if _, err := s.GetForActor(ctx, postID, actor); err != nil {
+		return nil, err
+	}
+	return s.revisionRepo.ListByPost(postID)
+}
+
+func (s *postService) GetRevision(ctx context.Context, postID uuid.UUID, revision int, actor Actor) (*models.PostRevision, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetRevision")
+	defer span.End()
+
+	if _, err := s.GetForActor(ctx, postID, actor); err != nil {
+		return nil, err
+	}
+	return s.revisionRepo.GetByRevision(postID, revision)
+}
+
+// DiffRevisions compares revision from with revision to. When to is 0 the
+// comparison is made against the current version of the post.
+func (s *postService) DiffRevisions(ctx context.Context, postID uuid.UUID, from, to int, actor Actor) (*RevisionDiff, error) {
+	ctx, span := tracing.Start(ctx, "PostService.DiffRevisions")
+	defer span.End()
+
+	if from <= 0 || to < 0 {
+		return nil, errors.New("invalid revision number")
+	}
+
+	post, err := s.GetForActor(ctx, postID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	oldRev, err := s.revisionRepo.GetByRevision(postID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var newRev *models.PostRevision
+	if to == 0 {
+		newRev = revisionFromPost(post)
+	} else {
+		newRev, err = s.revisionRepo.GetByRevision(postID, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	diff := &RevisionDiff{
+		From:   from,
+		To:     to,
+		Fields: []FieldChange{},
+		Lines:  DiffText(oldRev.Content, newRev.Content),
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"title", oldRev.Title, newRev.Title},
+		{"slug", oldRev.Slug, newRev.Slug},
+		{"excerpt", oldRev.Excerpt, newRev.Excerpt},
+		{"featured_img", oldRev.FeaturedImg, newRev.FeaturedImg},
+		{"status", string(oldRev.Status), string(newRev.Status)},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			diff.Fields = append(diff.Fields, FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+
+	for _, line := range diff.Lines {
+		switch line.Op {
+		case DiffInsert:
+			diff.Added++
+		case DiffDelete:
+			diff.Removed++
+		}
+	}
+
+	return diff, nil
+}
+
+// RestoreRevision copies the content, metadata, categories and tags of a revision
+// back onto the post. The post keeps its current status, and the version being
+// replaced is itself saved as a new revision, so a restore can be undone.
+// version is the version of the post the restore is based on, as for updates.
+func (s *postService) RestoreRevision(ctx context.Context, postID uuid.UUID, revision, version int, actor Actor) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.RestoreRevision")
+	defer span.End()
+
+	post, err := s.getEditable(ctx, postID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if post.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	rev, err := s.revisionRepo.GetByRevision(postID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Title = rev.Title
+	// Another post may have taken the slug since
+	slug, err := s.uniquePostSlug(ctx, rev.Slug, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	post.Slug = slug
+	post.Excerpt = rev.Excerpt
+	post.FeaturedImg = rev.FeaturedImg
+	post.Content = rev.Content
+	post.ContentHTML = s.markdownService.ToSafeHTML(rev.Content)
+	post.WordCount = s.calculateWordCount(rev.Content)
+	post.ReadingTime = s.calculateReadingTime(post.WordCount)
+
+	// Categories and tags deleted since the revision was taken are skipped
+	post.Categories = []models.Category{}
+	post.Tags = []models.Tag{}
+	if err := s.processCategories(post, &CreatePostRequest{CategoryIDs: rev.CategoryIDs}); err != nil {
+		return nil, err
+	}
+	if err := s.processTags(post, &CreatePostRequest{TagIDs: rev.TagIDs}); err != nil {
+		return nil, err
+	}
+
+	if err := s.postRepo.UpdateWithAssociations(ctx, post, actor.UserID); err != nil {
+		return nil, err
+	}
+	s.postsChanged(ctx)
+
+	return s.postRepo.GetByID(ctx, post.ID)
+}
+
+// revisionFromPost builds an unsaved revision mirroring the current post state
+func revisionFromPost(post *models.Post) *models.PostRevision {
+	rev := &models.PostRevision{
+		PostID:      post.ID,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Excerpt:     post.Excerpt,
+		Content:     post.Content,
+		FeaturedImg: post.FeaturedImg,
+		Status:      post.Status,
+	}
+	for _, category := range post.Categories {
+		rev.CategoryIDs = append(rev.CategoryIDs, category.ID.String())
+	}
+	for _, tag := range post.Tags {
+		rev.TagIDs = append(rev.TagIDs, tag.ID.String())
+	}
+	return rev
+}
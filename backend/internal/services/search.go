@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/repositories"
+)
+
+// Search Service
+type SearchService interface {
+	SearchPosts(query string, limit, offset int) ([]*models.PostSearchResult, int64, error)
+}
+
+type searchService struct {
+	searchRepo repositories.SearchRepository
+}
+
+// ErrEmptySearchQuery is returned when the search query has no terms
+var ErrEmptySearchQuery = errors.New("search query is required")
+
+const maxSearchQueryLength = 200
+
+func NewSearchService(searchRepo repositories.SearchRepository) SearchService {
+	return &searchService{searchRepo: searchRepo}
+}
+
+func (s *searchService) SearchPosts(query string, limit, offset int) ([]*models.PostSearchResult, int64, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, ErrEmptySearchQuery
+	}
+	if runes := []rune(query); len(runes) > maxSearchQueryLength {
+		query = string(runes[:maxSearchQueryLength])
+	}
+
+	return s.searchRepo.SearchPosts(query, limit, offset)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SiteURLs builds public links to pages of the blog frontend
+type SiteURLs struct {
+	base string
+}
+
+func NewSiteURLs(base string) SiteURLs {
+	return SiteURLs{base: strings.TrimRight(base, "/")}
+}
+
+func (u SiteURLs) Home() string {
+	return u.base + "/"
+}
+
+func (u SiteURLs) Post(slug string) string {
+	return u.base + "/blog/" + url.PathEscape(slug)
+}
+
+func (u SiteURLs) Category(slug string) string {
+	return u.base + "/categories/" + url.PathEscape(slug)
+}
+
+func (u SiteURLs) Tag(slug string) string {
+	return u.base + "/tags/" + url.PathEscape(slug)
+}
+
+// PasswordReset links to the frontend page where a new password is chosen
+func (u SiteURLs) PasswordReset(token string) string {
+	return u.base + "/reset-password?token=" + url.QueryEscape(token)
+}
+
+// VerifyEmail links to the frontend page that confirms an email address
+func (u SiteURLs) VerifyEmail(token string) string {
+	return u.base + "/verify-email?token=" + url.QueryEscape(token)
+}
+
+// Invitation links to the frontend registration page with an invitation
+func (u SiteURLs) Invitation(token string) string {
+	return u.base + "/register?invitation=" + url.QueryEscape(token)
+}
+
+// absoluteURL resolves ref against base, leaving absolute URLs untouched
+func absoluteURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil || refURL.IsAbs() {
+		return ref
+	}
+	baseURL, err := url.Parse(strings.TrimRight(base, "/") + "/")
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/config"
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/chmenegatti/myBlog/internal/repositories"
+	"github.com/chmenegatti/myBlog/internal/tracing"
+)
+
+// Limits from the sitemaps.org protocol and the Google image sitemap extension
+const (
+	sitemapMaxURLs     = 50000
+	sitemapMaxImages   = 1000
+	sitemapPostBatch   = 500
+	sitemapStaticPages = 3 // home, blog listing and categories listing
+	sitemapNamespace   = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapImageNS     = "http://www.google.com/schemas/sitemap-image/1.1"
+)
+
+// Sitemap Service
+type SitemapService interface {
+	PageCount(ctx context.Context) (int, error)
+	WriteIndex(w io.Writer, pages int) error
+	WritePage(ctx context.Context, w io.Writer, page int) error
+}
+
+type sitemapService struct {
+	postRepo        repositories.PostRepository
+	categoryRepo    repositories.CategoryRepository
+	tagRepo         repositories.TagRepository
+	markdownService MarkdownService
+	site            config.SiteConfig
+	urls            SiteURLs
+}
+
+type sitemapURL struct {
+	XMLName xml.Name       `xml:"url"`
+	Loc     string         `xml:"loc"`
+	LastMod string         `xml:"lastmod,omitempty"`
+	Images  []sitemapImage `xml:"image:image"`
+}
+
+type sitemapImage struct {
+	Loc string `xml:"image:loc"`
+}
+
+type sitemapRef struct {
+	XMLName xml.Name `xml:"sitemap"`
+	Loc     string   `xml:"loc"`
+}
+
+func NewSitemapService(postRepo repositories.PostRepository, categoryRepo repositories.CategoryRepository, tagRepo repositories.TagRepository, markdownService MarkdownService, site config.SiteConfig) SitemapService {
+	return &sitemapService{
+		postRepo:        postRepo,
+		categoryRepo:    categoryRepo,
+		tagRepo:         tagRepo,
+		markdownService: markdownService,
+		site:            site,
+		urls:            NewSiteURLs(site.URL),
+	}
+}
+
+// PageCount returns how many sitemap files are needed to list every URL
+func (s *sitemapService) PageCount(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "SitemapService.PageCount")
+	defer span.End()
+
+	total, err := s.countURLs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int((total + sitemapMaxURLs - 1) / sitemapMaxURLs), nil
+}
+
+// WriteIndex writes a sitemap index referencing pages sitemap files
+func (s *sitemapService) WriteIndex(w io.Writer, pages int) error {
+	enc, err := startSitemapDocument(w, "sitemapindex", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: sitemapNamespace})
+	if err != nil {
+		return err
+	}
+
+	for page := 1; page <= pages; page++ {
+		ref := sitemapRef{Loc: s.site.APIURL + "/sitemaps/" + strconv.Itoa(page) + ".xml"}
+		if err := enc.Encode(ref); err != nil {
+			return err
+		}
+	}
+
+	return endSitemapDocument(enc, "sitemapindex")
+}
+
+// WritePage writes the URLs of the given 1-based sitemap page. Static pages,
+// category and tag archives come first, followed by published posts.
+func (s *sitemapService) WritePage(ctx context.Context, w io.Writer, page int) error {
+	ctx, span := tracing.Start(ctx, "SitemapService.WritePage")
+	defer span.End()
+
+	start := (page - 1) * sitemapMaxURLs
+	end := start + sitemapMaxURLs
+
+	fixed, err := s.fixedURLs()
+	if err != nil {
+		return err
+	}
+
+	enc, err := startSitemapDocument(w, "urlset",
+		xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: sitemapNamespace},
+		xml.Attr{Name: xml.Name{Local: "xmlns:image"}, Value: sitemapImageNS},
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := start; i < end && i < len(fixed); i++ {
+		if err := enc.Encode(fixed[i]); err != nil {
+			return err
+		}
+	}
+
+	// Translate the page window into an offset within the post list
+	offset := max(start-len(fixed), 0)
+	remaining := end - max(start, len(fixed))
+	for remaining > 0 {
+		posts, err := s.postRepo.ListPublishedURLs(ctx, min(sitemapPostBatch, remaining), offset)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range posts {
+			if err := enc.Encode(s.postURL(post)); err != nil {
+				return err
+			}
+		}
+
+		if len(posts) < sitemapPostBatch {
+			break
+		}
+		offset += len(posts)
+		remaining -= len(posts)
+	}
+
+	return endSitemapDocument(enc, "urlset")
+}
+
+func (s *sitemapService) countURLs(ctx context.Context) (int64, error) {
+	posts, err := s.postRepo.CountPublished(ctx)
+	if err != nil {
+		return 0, err
+	}
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return 0, err
+	}
+	tags, err := s.tagRepo.List()
+	if err != nil {
+		return 0, err
+	}
+	return sitemapStaticPages + int64(len(categories)) + int64(len(tags)) + posts, nil
+}
+
+// fixedURLs returns the static pages and the category and tag archives
+func (s *sitemapService) fixedURLs() ([]sitemapURL, error) {
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.tagRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]sitemapURL, 0, sitemapStaticPages+len(categories)+len(tags))
+	urls = append(urls,
+		sitemapURL{Loc: s.urls.Home()},
+		sitemapURL{Loc: s.site.URL + "/blog"},
+		sitemapURL{Loc: s.site.URL + "/categories"},
+	)
+	for _, category := range categories {
+		urls = append(urls, sitemapURL{
+			Loc:     s.urls.Category(category.Slug),
+			LastMod: category.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	for _, tag := range tags {
+		urls = append(urls, sitemapURL{
+			Loc:     s.urls.Tag(tag.Slug),
+			LastMod: tag.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return urls, nil
+}
+
+// postURL builds a post entry including its featured image and the images
+// referenced from its markdown content
+func (s *sitemapService) postURL(post *models.Post) sitemapURL {
+	entry := sitemapURL{
+		Loc:     s.urls.Post(post.Slug),
+		LastMod: post.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+
+	seen := make(map[string]bool)
+	candidates := append([]string{post.FeaturedImg}, s.markdownService.ExtractImages(post.Content)...)
+	for _, candidate := range candidates {
+		image := absoluteURL(s.site.APIURL, candidate)
+		if !strings.HasPrefix(image, "http://") && !strings.HasPrefix(image, "https://") || seen[image] {
+			continue
+		}
+		seen[image] = true
+		entry.Images = append(entry.Images, sitemapImage{Loc: image})
+		if len(entry.Images) == sitemapMaxImages {
+			break
+		}
+	}
+
+	return entry
+}
+
+func startSitemapDocument(w io.Writer, root string, attrs ...xml.Attr) (*xml.Encoder, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+	enc := xml.NewEncoder(w)
+	return enc, enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: root}, Attr: attrs})
+}
+
+func endSitemapDocument(enc *xml.Encoder, root string) error {
+	if err := enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: root}}); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
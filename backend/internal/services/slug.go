@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSlugLength keeps slugs short enough for readable URLs
+const maxSlugLength = 80
+
+// slugTransliterations covers letters that do not decompose into an ASCII base
+// letter plus combining marks
+var slugTransliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+}
+
+// generateSlug turns a title into a lowercase ASCII slug: diacritics are
+// transliterated ("Concorrência" becomes "concorrencia"), every other character
+// outside [a-z0-9] becomes a separator and the result is cut at maxSlugLength
+// on a word boundary
+func generateSlug(title string) string {
+	var b strings.Builder
+	pendingHyphen := false
+
+	write := func(s string) {
+		if pendingHyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingHyphen = false
+		b.WriteString(s)
+	}
+
+	// NFD splits "ê" into "e" followed by a combining circumflex, which is dropped
+	for _, r := range norm.NFD.String(strings.ToLower(title)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			write(string(r))
+		case unicode.Is(unicode.Mn, r):
+			// combining mark, part of the previous letter
+		case r == '\'' || r == '’':
+			// apostrophes join words: "don't" becomes "dont"
+		default:
+			if t, ok := slugTransliterations[r]; ok {
+				write(t)
+			} else {
+				pendingHyphen = true
+			}
+		}
+	}
+
+	return truncateSlug(b.String(), maxSlugLength)
+}
+
+// truncateSlug shortens slug to at most n bytes, preferring to cut at a hyphen
+func truncateSlug(slug string, n int) string {
+	if len(slug) <= n {
+		return slug
+	}
+	slug = slug[:n]
+	if i := strings.LastIndexByte(slug, '-'); i > n/2 {
+		slug = slug[:i]
+	}
+	return strings.TrimRight(slug, "-")
+}
+
+// uniqueSlug returns base, or base with a "-2", "-3"... suffix when taken
+// reports it as already in use. fallback is used when base is empty, e.g. for a
+// title written only in a non-Latin script.
+func uniqueSlug(base, fallback string, taken func(slug string) (bool, error)) (string, error) {
+	if base == "" {
+		base = fallback
+	}
+
+	slug := base
+	for n := 2; ; n++ {
+		exists, err := taken(slug)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		suffix := fmt.Sprintf("-%d", n)
+		slug = truncateSlug(base, maxSlugLength-len(suffix)) + suffix
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSlug(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"plain", "Hello World", "hello-world"},
+		{"accents", "Concorrência em Go: Canais e Goroutines", "concorrencia-em-go-canais-e-goroutines"},
+		{"cedilla and tilde", "Ação, Configuração e Migração", "acao-configuracao-e-migracao"},
+		{"uppercase accents", "ÉPOCA ÀS VEZES", "epoca-as-vezes"},
+		{"transliterated letters", "Straße, Æsir, Øre, Łódź", "strasse-aesir-ore-lodz"},
+		{"apostrophes", "Don't stop – it’s fine", "dont-stop-its-fine"},
+		{"separators collapse", "  Go -- 1.24   released!!  ", "go-1-24-released"},
+		{"digits", "Top 10 dicas de 2024", "top-10-dicas-de-2024"},
+		{"only non-Latin", "日本語のブログ", ""},
+		{"only punctuation", "?!…", ""},
+		{"empty", "", ""},
+		{"decomposed input", "Concorre\u0302ncia", "concorrencia"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateSlug(tt.title); got != tt.want {
+				t.Errorf("generateSlug(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSlugTruncates(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{
+			"cut at a word boundary",
+			strings.Repeat("palavra ", 20),
+			strings.TrimSuffix(strings.Repeat("palavra-", 10), "-"),
+		},
+		{
+			"one long word is cut mid-word",
+			strings.Repeat("a", 100),
+			strings.Repeat("a", maxSlugLength),
+		},
+		{
+			"accents count once transliterated",
+			strings.Repeat("ação ", 20),
+			strings.TrimSuffix(strings.Repeat("acao-", 16), "-"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateSlug(tt.title)
+			if got != tt.want {
+				t.Errorf("generateSlug = %q, want %q", got, tt.want)
+			}
+			if len(got) > maxSlugLength || strings.HasSuffix(got, "-") {
+				t.Errorf("slug %q is longer than %d or ends with a hyphen", got, maxSlugLength)
+			}
+		})
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	long := strings.Repeat("a", maxSlugLength)
+	tests := []struct {
+		name     string
+		base     string
+		fallback string
+		taken    []string
+		want     string
+	}{
+		{"free", "hello", "post", nil, "hello"},
+		{"first collision", "hello", "post", []string{"hello"}, "hello-2"},
+		{"several collisions", "hello", "post", []string{"hello", "hello-2", "hello-3"}, "hello-4"},
+		{"other slugs do not matter", "hello", "post", []string{"hello-2"}, "hello"},
+		{"empty base uses fallback", "", "post-1a2b3c", nil, "post-1a2b3c"},
+		{"fallback collides", "", "post", []string{"post"}, "post-2"},
+		{"suffix fits the length limit", long, "post", []string{long}, strings.Repeat("a", maxSlugLength-2) + "-2"},
+		{"longer suffix", long, "post", append([]string{long}, suffixed(long, 2, 9)...), strings.Repeat("a", maxSlugLength-3) + "-10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := make(map[string]bool)
+			for _, slug := range tt.taken {
+				taken[slug] = true
+			}
+			got, err := uniqueSlug(tt.base, tt.fallback, func(slug string) (bool, error) {
+				return taken[slug], nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("uniqueSlug = %q, want %q", got, tt.want)
+			}
+			if len(got) > maxSlugLength {
+				t.Errorf("slug %q is longer than %d", got, maxSlugLength)
+			}
+		})
+	}
+}
+
+// suffixed returns the slugs uniqueSlug tries for base with suffixes from..to
+func suffixed(base string, from, to int) []string {
+	var slugs []string
+	for n := from; n <= to; n++ {
+		suffix := "-" + string(rune('0'+n))
+		slugs = append(slugs, truncateSlug(base, maxSlugLength-len(suffix))+suffix)
+	}
+	return slugs
+}
+
+func TestUniqueSlugError(t *testing.T) {
+	lookupErr := errors.New("connection reset")
+	_, err := uniqueSlug("hello", "post", func(string) (bool, error) { return false, lookupErr })
+	if !errors.Is(err, lookupErr) {
+		t.Errorf("err = %v, want %v", err, lookupErr)
+	}
+}
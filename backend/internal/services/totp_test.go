@@ -0,0 +1,124 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 appendix B
+const rfc6238Secret = "12345678901234567890"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B vectors, truncated to six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode([]byte(rfc6238Secret), tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte(rfc6238Secret))
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	key := []byte(rfc6238Secret)
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", secret, totpCode(key, step), step, true},
+		{"previous step", secret, totpCode(key, step-1), step - 1, true},
+		{"next step", secret, totpCode(key, step+1), step + 1, true},
+		{"two steps behind", secret, totpCode(key, step-2), 0, false},
+		{"two steps ahead", secret, totpCode(key, step+2), 0, false},
+		{"spaces", secret, " 050 471 ", step, true},
+		{"lowercase secret", strings.ToLower(secret), "050471", step, true},
+		{"wrong code", secret, "000000", 0, false},
+		{"too short", secret, "05047", 0, false},
+		{"too long", secret, "0504710", 0, false},
+		{"bad secret", "not base32!", "050471", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := verifyTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("verifyTOTP(%q) = (%d, %v), want (%d, %v)", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not unpadded base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret is %d bytes, want 20", len(key))
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	got := totpURI("My Blog", "ana@example.com", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/My%20Blog:ana@example.com?algorithm=SHA1&digits=6&issuer=My+Blog&period=30&secret=JBSWY3DPEHPK3PXP"
+	if got != want {
+		t.Errorf("totpURI = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	for range 100 {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("recovery code %q is not xxxxx-xxxxx", code)
+		}
+		for _, c := range code[:5] + code[6:] {
+			if !strings.ContainsRune(recoveryCodeAlphabet, c) {
+				t.Fatalf("recovery code %q has %q outside the alphabet", code, c)
+			}
+		}
+		if normalizeRecoveryCode(code) != strings.ReplaceAll(code, "-", "") {
+			t.Errorf("normalizeRecoveryCode(%q) = %q", code, normalizeRecoveryCode(code))
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"abcde-fghjk", "abcdefghjk"},
+		{"ABCDE-FGHJK", "abcdefghjk"},
+		{"  abcde fghjk\n", "abcdefghjk"},
+		{"ab-cd-ef", "abcdef"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.in); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chmenegatti/myBlog/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Two-factor authentication errors
+var (
+	ErrInvalidChallenge        = errors.New("invalid or expired login challenge")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp       = errors.New("two-factor authentication has not been set up")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for your role")
+)
+
+// Challenge tokens are JWTs restricted by audience so they can never be used
+// as access tokens
+const (
+	audienceTwoFactorLogin  = "2fa-login"
+	audienceTwoFactorEnroll = "2fa-enroll"
+	challengeExpiration     = 5 * time.Minute
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789" // no look-alike characters
+)
+
+// TOTPSetup is what an authenticator app needs to enroll. URL is the otpauth://
+// URI to render as a QR code; Secret is for manual entry.
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URL    string `json:"otpauth_url"`
+}
+
+// LoginTwoFactor completes a login started with a password, using either a
+// TOTP code or a recovery code
+func (s *authService) LoginTwoFactor(challengeToken, code, recoveryCode string, client ClientInfo) (*LoginResponse, error) {
+	claims, err := validateChallengeJWT(challengeToken, s.keys, audienceTwoFactorLogin)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil || !user.IsActive || !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.checkLoginAllowed(user, client, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(user, code, recoveryCode); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			return nil, s.loginFailed(user, client, err)
+		}
+		return nil, err
+	}
+
+	return s.startSession(user, client)
+}
+
+// LoginAfterEnrollment opens the session of a user who was required to enroll
+// during login and has just confirmed their authenticator
+func (s *authService) LoginAfterEnrollment(userID uuid.UUID, client ClientInfo) (*LoginResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || !user.IsActive || !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+	return s.startSession(user, client)
+}
+
+// SetupTOTP generates a new secret for the user. It is not used for login
+// until confirmed with ConfirmTOTP.
+func (s *authService) SetupTOTP(userID uuid.UUID) (*TOTPSetup, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = secret
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &TOTPSetup{
+		Secret: secret,
+		URL:    totpURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their
+// authenticator works, and returns a fresh set of recovery codes
+func (s *authService) ConfirmTOTP(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	step, ok := verifyTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(user.ID)
+}
+
+// DisableTOTP turns two-factor authentication off after checking the password
+// and a current code
+func (s *authService) DisableTOTP(userID uuid.UUID, password, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if s.twoFactorRequired(user) {
+		return ErrTwoFactorRequired
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return errors.New("invalid credentials")
+	}
+	if err := s.verifySecondFactor(user, code, ""); err != nil {
+		return err
+	}
+
+	// Reload so the time step recorded by the verification is not overwritten
+	user, err = s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	return s.recoveryCodeRepo.DeleteForUser(user.ID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, e.g. after most
+// of them were used
+func (s *authService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verifySecondFactor(user, code, ""); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(user.ID)
+}
+
+// verifySecondFactor accepts a TOTP code, or a recovery code when no TOTP code
+// is given. Both are single use.
+func (s *authService) verifySecondFactor(user *models.User, code, recoveryCode string) error {
+	if code != "" {
+		step, ok := verifyTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		fresh, err := s.userRepo.UseTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	if recoveryCode == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	codes, err := s.recoveryCodeRepo.ListUnused(user.ID)
+	if err != nil {
+		return err
+	}
+	normalized := normalizeRecoveryCode(recoveryCode)
+	for _, stored := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(stored.CodeHash), []byte(normalized)) != nil {
+			continue
+		}
+		used, err := s.recoveryCodeRepo.MarkUsed(stored.ID)
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+		break
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// twoFactorRequired reports whether the policy requires 2FA for the user's role
+func (s *authService) twoFactorRequired(user *models.User) bool {
+	for _, role := range s.authCfg.TwoFactorRequiredRoles {
+		if models.UserRole(role) == user.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// challengeResponse answers a password login that still needs a second factor
+func (s *authService) challengeResponse(user *models.User, audience string) (*LoginResponse, error) {
+	claims := JWTClaims{
+		UserID: user.ID,
+		Role:   user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.keys.issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(challengeExpiration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		TwoFactorRequired:      audience == audienceTwoFactorLogin,
+		TwoFactorSetupRequired: audience == audienceTwoFactorEnroll,
+		ChallengeToken:         token,
+		ExpiresIn:              int(challengeExpiration.Seconds()),
+	}, nil
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns them in
+// clear text; they cannot be shown again
+func (s *authService) newRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]*models.RecoveryCode, recoveryCodeCount)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(normalizeRecoveryCode(code)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = &models.RecoveryCode{UserID: userID, CodeHash: string(hash)}
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, c := range buf {
+		if i == 5 {
+			b.WriteByte('-')
+		}
+		// 256 is not a multiple of the alphabet size; the bias is negligible here
+		b.WriteByte(recoveryCodeAlphabet[int(c)%len(recoveryCodeAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// normalizeRecoveryCode makes the comparison ignore case, spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// validateChallengeJWT validates a two-factor challenge token for the given audience
+func validateChallengeJWT(tokenString string, keys *KeySet, audience string) (*JWTClaims, error) {
+	token, err := keys.parse(tokenString, &JWTClaims{}, jwt.WithAudience(audience))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+// ValidateEnrollmentJWT validates the challenge handed to users who must enroll
+// in two-factor authentication before they can log in
+func ValidateEnrollmentJWT(tokenString string, keys *KeySet) (*JWTClaims, error) {
+	return validateChallengeJWT(tokenString, keys, audienceTwoFactorEnroll)
+}
@@ -1,13 +1,22 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"log"
 	"math"
 	"strings"
+	"time"
 
+	"github.com/chmenegatti/myBlog/internal/cache"
+	"github.com/chmenegatti/myBlog/internal/logger"
+	"github.com/chmenegatti/myBlog/internal/metrics"
 	"github.com/chmenegatti/myBlog/internal/models"
 	"github.com/chmenegatti/myBlog/internal/repositories"
+	"github.com/chmenegatti/myBlog/internal/tracing"
 	"github.com/google/uuid"
 )
 
@@ -20,6 +29,9 @@ type UserService interface {
 	CreateUser(req *CreateUserRequest) (*models.User, error)
 }
 
+// ErrInvalidRole is returned for a role outside the permission matrix
+var ErrInvalidRole = errors.New("invalid role")
+
 type userService struct {
 	userRepo repositories.UserRepository
 }
@@ -61,11 +73,19 @@ func (s *userService) CreateUser(req *CreateUserRequest) (*models.User, error) {
 		return nil, errors.New("username already exists")
 	}
 
+	role := req.Role
+	if role == "" {
+		role = models.RoleAuthor
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
 	user := &models.User{
 		Username: req.Username,
 		Email:    req.Email,
 		Name:     req.Name,
-		Role:     req.Role,
+		Role:     role,
 		IsActive: true,
 	}
 
@@ -78,53 +98,95 @@ func (s *userService) CreateUser(req *CreateUserRequest) (*models.User, error) {
 
 // Post Service
 type PostService interface {
-	Create(req *CreatePostRequest, authorID uuid.UUID) (*models.Post, error)
-	GetByID(id uuid.UUID) (*models.Post, error)
-	GetBySlug(slug string) (*models.Post, error)
-	Update(post *models.Post) error
-	UpdateWithAssociations(id uuid.UUID, req *UpdatePostRequest) (*models.Post, error)
-	Delete(id uuid.UUID) error
-	List(limit, offset int, status models.PostStatus) ([]*models.Post, int64, error)
-	GetPublished(limit, offset int) ([]*models.Post, int64, error)
-	Publish(id uuid.UUID) error
-	Unpublish(id uuid.UUID) error
+	Create(ctx context.Context, req *CreatePostRequest, actor Actor) (*models.Post, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error)
+	GetForActor(ctx context.Context, id uuid.UUID, actor Actor) (*models.Post, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Post, error)
+	ResolveSlug(ctx context.Context, slug string) (string, bool, error)
+	Update(ctx context.Context, post *models.Post) error
+	UpdateWithAssociations(ctx context.Context, id uuid.UUID, req *UpdatePostRequest, actor Actor) (*models.Post, error)
+	Delete(ctx context.Context, id uuid.UUID, actor Actor) error
+	List(ctx context.Context, limit, offset int, status models.PostStatus, actor Actor) ([]*models.Post, int64, error)
+	GetPublished(ctx context.Context, filter PostFilter, limit, offset int) ([]*models.Post, int64, error)
+	Publish(ctx context.Context, id uuid.UUID, actor Actor) error
+	Unpublish(ctx context.Context, id uuid.UUID, actor Actor) error
+	PublishDue(ctx context.Context, now time.Time) (int, error)
+	ListRevisions(ctx context.Context, postID uuid.UUID, actor Actor) ([]*models.PostRevision, error)
+	GetRevision(ctx context.Context, postID uuid.UUID, revision int, actor Actor) (*models.PostRevision, error)
+	DiffRevisions(ctx context.Context, postID uuid.UUID, from, to int, actor Actor) (*RevisionDiff, error)
+	RestoreRevision(ctx context.Context, postID uuid.UUID, revision, version int, actor Actor) (*models.Post, error)
 }
 
+// PostFilter narrows down published post listings
+type PostFilter = repositories.PostFilter
+
+// Post scheduling errors
+var (
+	ErrInvalidPostStatus   = errors.New("invalid post status")
+	ErrPublishAtRequired   = errors.New("publish_at is required for scheduled posts")
+	ErrPublishAtNotAllowed = errors.New("publish_at can only be set on scheduled posts")
+)
+
+// ErrPostNotFound is returned when a slug does not lead to a published post
+var ErrPostNotFound = errors.New("post not found")
+
+// ErrVersionConflict is returned when a post, category, tag or comment was
+// updated by someone else since the version an update is based on
+var ErrVersionConflict = repositories.ErrVersionConflict
+
 type postService struct {
 	postRepo        repositories.PostRepository
 	categoryRepo    repositories.CategoryRepository
 	tagRepo         repositories.TagRepository
+	revisionRepo    repositories.PostRevisionRepository
 	markdownService MarkdownService
+	cache           *cache.Cache
 }
 
 type CreatePostRequest struct {
-	Title       string   `json:"title" binding:"required"`
-	Content     string   `json:"content" binding:"required"`
-	Excerpt     string   `json:"excerpt"`
-	FeaturedImg string   `json:"featured_img"` // Changed to match frontend field name
-	Category    string   `json:"category"`     // Category name (single)
-	Tags        string   `json:"tags"`         // Comma-separated tag names
-	CategoryIDs []string `json:"category_ids"` // Optional: UUID strings for categories
-	TagIDs      []string `json:"tag_ids"`      // Optional: UUID strings for tags
+	Title       string     `json:"title" binding:"required"`
+	Content     string     `json:"content" binding:"required"`
+	Excerpt     string     `json:"excerpt"`
+	FeaturedImg string     `json:"featured_img"` // Changed to match frontend field name
+	Category    string     `json:"category"`     // Category name (single)
+	Tags        string     `json:"tags"`         // Comma-separated tag names
+	CategoryIDs []string   `json:"category_ids"` // Optional: UUID strings for categories
+	TagIDs      []string   `json:"tag_ids"`      // Optional: UUID strings for tags
+	PublishAt   *time.Time `json:"publish_at"`   // Optional: schedules the post for publication
 }
 
-func NewPostService(postRepo repositories.PostRepository, categoryRepo repositories.CategoryRepository, tagRepo repositories.TagRepository) PostService {
+func NewPostService(postRepo repositories.PostRepository, categoryRepo repositories.CategoryRepository, tagRepo repositories.TagRepository, revisionRepo repositories.PostRevisionRepository, responseCache *cache.Cache) PostService {
 	return &postService{
 		postRepo:        postRepo,
 		categoryRepo:    categoryRepo,
 		tagRepo:         tagRepo,
+		revisionRepo:    revisionRepo,
 		markdownService: NewMarkdownService(),
+		cache:           responseCache,
 	}
 }
 
-func (s *postService) Create(req *CreatePostRequest, authorID uuid.UUID) (*models.Post, error) {
+func (s *postService) Create(ctx context.Context, req *CreatePostRequest, actor Actor) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.Create")
+	defer span.End()
+
+	if !actor.Can(models.PermCreatePosts) {
+		return nil, ErrForbidden
+	}
+	if req.PublishAt != nil && !actor.canSetStatus(models.StatusScheduled) {
+		return nil, ErrForbidden
+	}
+
 	// Validate markdown content
 	if err := s.markdownService.ValidateMarkdown(req.Content); err != nil {
 		return nil, err
 	}
 
 	// Generate slug from title
-	slug := generateSlug(req.Title)
+	slug, err := s.uniquePostSlug(ctx, generateSlug(req.Title), uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
 
 	// Process markdown content
 	contentHTML := s.markdownService.ToSafeHTML(req.Content)
@@ -146,12 +208,18 @@ func (s *postService) Create(req *CreatePostRequest, authorID uuid.UUID) (*model
 		ContentHTML: contentHTML,
 		Excerpt:     excerpt,
 		FeaturedImg: req.FeaturedImg,
-		AuthorID:    authorID,
+		AuthorID:    actor.UserID,
 		Status:      models.StatusDraft,
 		WordCount:   wordCount,
 		ReadingTime: readingTime,
 	}
 
+	if req.PublishAt != nil {
+		if err := applyStatus(post, models.StatusScheduled, req.PublishAt); err != nil {
+			return nil, err
+		}
+	}
+
 	// Process categories and tags before creating
 	if err := s.processCategories(post, req); err != nil {
 		return nil, err
@@ -162,91 +230,300 @@ func (s *postService) Create(req *CreatePostRequest, authorID uuid.UUID) (*model
 	}
 
 	// Create the post with associations
-	if err := s.postRepo.CreateWithAssociations(post); err != nil {
+	if err := s.postRepo.CreateWithAssociations(ctx, post); err != nil {
 		return nil, err
 	}
 
 	// Reload post with associations
-	return s.postRepo.GetByID(post.ID)
+	return s.postRepo.GetByID(ctx, post.ID)
+}
+
+func (s *postService) GetByID(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetByID")
+	defer span.End()
+
+	return s.postRepo.GetByID(ctx, id)
+}
+
+// GetForActor returns a post for the admin area, where only editors see
+// other people's posts
+func (s *postService) GetForActor(ctx context.Context, id uuid.UUID, actor Actor) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetForActor")
+	defer span.End()
+
+	post, err := s.postRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.canViewPost(post) {
+		return nil, ErrForbidden
+	}
+	return post, nil
 }
 
-func (s *postService) GetByID(id uuid.UUID) (*models.Post, error) {
-	return s.postRepo.GetByID(id)
+// getEditable loads a post the actor is allowed to modify
+func (s *postService) getEditable(ctx context.Context, id uuid.UUID, actor Actor) (*models.Post, error) {
+	post, err := s.postRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.canEditPost(post) {
+		return nil, ErrForbidden
+	}
+	return post, nil
 }
 
-func (s *postService) GetBySlug(slug string) (*models.Post, error) {
-	post, err := s.postRepo.GetBySlug(slug)
+func (s *postService) GetBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetBySlug")
+	defer span.End()
+
+	post, err := s.publishedBySlug(ctx, slug)
 	if err != nil {
 		return nil, err
 	}
 
-	// Increment view count
-	s.postRepo.IncrementViewCount(post.ID)
+	// Increment view count, on cache hits too
+	s.postRepo.IncrementViewCount(ctx, post.ID)
 
 	return post, nil
 }
 
-func (s *postService) Update(post *models.Post) error {
-	return s.postRepo.Update(post)
+// publishedBySlug loads a published post through the cache. The view count of
+// a cached post lags behind by up to CACHE_TTL.
+func (s *postService) publishedBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	return cache.Fetch(ctx, s.cache, cache.AreaPosts, "slug:"+slug, func(ctx context.Context) (*models.Post, error) {
+		return s.postRepo.GetBySlug(ctx, slug)
+	})
 }
 
-func (s *postService) Delete(id uuid.UUID) error {
-	return s.postRepo.Delete(id)
+// ResolveSlug returns the current slug of the published post known by slug and
+// whether slug is an old one that should be redirected
+func (s *postService) ResolveSlug(ctx context.Context, slug string) (string, bool, error) {
+	ctx, span := tracing.Start(ctx, "PostService.ResolveSlug")
+	defer span.End()
+
+	if post, err := s.publishedBySlug(ctx, slug); err == nil {
+		return post.Slug, false, nil
+	}
+
+	postID, err := s.postRepo.GetPostIDBySlugHistory(ctx, slug)
+	if err != nil {
+		return "", false, ErrPostNotFound
+	}
+
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil || post.Status != models.StatusPublished {
+		return "", false, ErrPostNotFound
+	}
+
+	return post.Slug, true, nil
 }
 
-func (s *postService) List(limit, offset int, status models.PostStatus) ([]*models.Post, int64, error) {
-	return s.postRepo.List(limit, offset, status)
+func (s *postService) Update(ctx context.Context, post *models.Post) error {
+	ctx, span := tracing.Start(ctx, "PostService.Update")
+	defer span.End()
+
+	if err := s.postRepo.Update(ctx, post); err != nil {
+		return err
+	}
+	s.postsChanged(ctx)
+	return nil
 }
 
-func (s *postService) GetPublished(limit, offset int) ([]*models.Post, int64, error) {
-	return s.postRepo.GetPublished(limit, offset)
+func (s *postService) Delete(ctx context.Context, id uuid.UUID, actor Actor) error {
+	ctx, span := tracing.Start(ctx, "PostService.Delete")
+	defer span.End()
+
+	if _, err := s.getEditable(ctx, id, actor); err != nil {
+		return err
+	}
+	if err := s.postRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.postsChanged(ctx)
+	return nil
 }
 
-func (s *postService) Publish(id uuid.UUID) error {
-	post, err := s.postRepo.GetByID(id)
+// List returns every post to editors and only the actor's own posts to others
+func (s *postService) List(ctx context.Context, limit, offset int, status models.PostStatus, actor Actor) ([]*models.Post, int64, error) {
+	ctx, span := tracing.Start(ctx, "PostService.List")
+	defer span.End()
+
+	authorID := actor.UserID
+	if actor.Can(models.PermManagePosts) {
+		authorID = uuid.Nil
+	}
+	return s.postRepo.List(ctx, limit, offset, status, authorID)
+}
+
+func (s *postService) GetPublished(ctx context.Context, filter PostFilter, limit, offset int) ([]*models.Post, int64, error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPublished")
+	defer span.End()
+
+	key, err := publishedKey(filter, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := cache.Fetch(ctx, s.cache, cache.AreaPosts, key, func(ctx context.Context) (publishedPage, error) {
+		posts, total, err := s.postRepo.GetPublished(ctx, filter, limit, offset)
+		return publishedPage{Posts: posts, Total: total}, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return page.Posts, page.Total, nil
+}
+
+// publishedPage is a page of published posts as it is cached
+type publishedPage struct {
+	Posts []*models.Post `json:"posts"`
+	Total int64          `json:"total"`
+}
+
+// publishedKey identifies a page of published posts in the cache
+func publishedKey(filter PostFilter, limit, offset int) (string, error) {
+	data, err := json.Marshal(struct {
+		Filter PostFilter
+		Limit  int
+		Offset int
+	}{filter, limit, offset})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "published:" + hex.EncodeToString(sum[:]), nil
+}
+
+// postsChanged drops the cached public reads after a post was written. Posts
+// that are not public are cheap to include, and an edit can always make one
+// public or hide it again.
+func (s *postService) postsChanged(ctx context.Context) {
+	s.cache.Invalidate(ctx, cache.AreaPosts)
+}
+
+func (s *postService) Publish(ctx context.Context, id uuid.UUID, actor Actor) error {
+	ctx, span := tracing.Start(ctx, "PostService.Publish")
+	defer span.End()
+
+	if !actor.canSetStatus(models.StatusPublished) {
+		return ErrForbidden
+	}
+
+	post, err := s.getEditable(ctx, id, actor)
 	if err != nil {
 		return err
 	}
 
-	post.Status = models.StatusPublished
-	return s.postRepo.Update(post)
+	wasPublished := post.Status == models.StatusPublished
+	if err := applyStatus(post, models.StatusPublished, nil); err != nil {
+		return err
+	}
+	if err := s.postRepo.Update(ctx, post); err != nil {
+		return err
+	}
+	s.postsChanged(ctx)
+	if !wasPublished {
+		metrics.PostsPublished.WithLabelValues("manual").Inc()
+	}
+	return nil
 }
 
-func (s *postService) Unpublish(id uuid.UUID) error {
-	post, err := s.postRepo.GetByID(id)
+func (s *postService) Unpublish(ctx context.Context, id uuid.UUID, actor Actor) error {
+	ctx, span := tracing.Start(ctx, "PostService.Unpublish")
+	defer span.End()
+
+	post, err := s.getEditable(ctx, id, actor)
 	if err != nil {
 		return err
 	}
 
-	post.Status = models.StatusDraft
-	return s.postRepo.Update(post)
+	if err := applyStatus(post, models.StatusDraft, nil); err != nil {
+		return err
+	}
+	if err := s.postRepo.Update(ctx, post); err != nil {
+		return err
+	}
+	s.postsChanged(ctx)
+	return nil
+}
+
+// PublishDue promotes every scheduled post whose publish_at is not after now
+// and returns how many posts were published
+func (s *postService) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "PostService.PublishDue")
+	defer span.End()
+
+	const batchSize = 100
+
+	published := 0
+	for {
+		posts, err := s.postRepo.GetDueScheduled(ctx, now, batchSize)
+		if err != nil {
+			return published, err
+		}
+
+		promoted := 0
+		for _, post := range posts {
+			ok, err := s.postRepo.PublishScheduled(ctx, post.ID, now)
+			if err != nil {
+				return published, err
+			}
+			if ok {
+				promoted++
+				logger.WithPost(post.ID.String(), post.Title, "scheduled_publish").Info("Scheduled post published")
+			}
+		}
+		published += promoted
+		metrics.PostsPublished.WithLabelValues("scheduled").Add(float64(promoted))
+		if promoted > 0 {
+			s.postsChanged(ctx)
+		}
+
+		// Stop when the batch was not full or another instance took every post in it
+		if len(posts) < batchSize || promoted == 0 {
+			return published, nil
+		}
+	}
 }
 
 // UpdatePostRequest for updating posts with categories and tags
 type UpdatePostRequest struct {
-	Title       string `json:"title"`
-	Slug        string `json:"slug"`
-	Content     string `json:"content"`
-	Excerpt     string `json:"excerpt"`
-	FeaturedImg string `json:"featured_img"` // Changed to match frontend field name
-	Status      string `json:"status"`
-	Category    string `json:"category"` // Category name
-	Tags        string `json:"tags"`     // Comma-separated tag names
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Content     string     `json:"content"`
+	Excerpt     string     `json:"excerpt"`
+	FeaturedImg string     `json:"featured_img"` // Changed to match frontend field name
+	Status      string     `json:"status"`
+	Category    string     `json:"category"`   // Category name
+	Tags        string     `json:"tags"`       // Comma-separated tag names
+	PublishAt   *time.Time `json:"publish_at"` // Schedules the post when status is "scheduled" or empty
+	Version     int        `json:"version"`    // Version of the post the update is based on
 }
 
 // UpdateWithAssociations updates a post and its categories/tags
-func (s *postService) UpdateWithAssociations(id uuid.UUID, req *UpdatePostRequest) (*models.Post, error) {
-	post, err := s.postRepo.GetByID(id)
+func (s *postService) UpdateWithAssociations(ctx context.Context, id uuid.UUID, req *UpdatePostRequest, actor Actor) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "PostService.UpdateWithAssociations")
+	defer span.End()
+
+	post, err := s.getEditable(ctx, id, actor)
 	if err != nil {
 		return nil, err
 	}
+	if post.Version != req.Version {
+		return nil, ErrVersionConflict
+	}
+	wasPublished := post.Status == models.StatusPublished
 
 	// Update basic fields
 	if req.Title != "" {
 		post.Title = req.Title
 	}
 	if req.Slug != "" {
-		post.Slug = req.Slug
+		slug, err := s.uniquePostSlug(ctx, generateSlug(req.Slug), post.ID)
+		if err != nil {
+			return nil, err
+		}
+		post.Slug = slug
 	}
 	if req.Content != "" {
 		post.Content = req.Content
@@ -256,8 +533,17 @@ func (s *postService) UpdateWithAssociations(id uuid.UUID, req *UpdatePostReques
 		post.WordCount = s.calculateWordCount(req.Content)
 		post.ReadingTime = s.calculateReadingTime(post.WordCount)
 	}
-	if req.Status != "" {
-		post.Status = models.PostStatus(req.Status)
+	if req.Status != "" || req.PublishAt != nil {
+		status := models.PostStatus(req.Status)
+		if status == "" {
+			status = models.StatusScheduled
+		}
+		if !actor.canSetStatus(status) {
+			return nil, ErrForbidden
+		}
+		if err := applyStatus(post, status, req.PublishAt); err != nil {
+			return nil, err
+		}
 	}
 	post.Excerpt = req.Excerpt
 	post.FeaturedImg = req.FeaturedImg
@@ -281,26 +567,55 @@ func (s *postService) UpdateWithAssociations(id uuid.UUID, req *UpdatePostReques
 	}
 
 	// Update the post
-	if err := s.postRepo.UpdateWithAssociations(post); err != nil {
+	if err := s.postRepo.UpdateWithAssociations(ctx, post, actor.UserID); err != nil {
 		return nil, err
 	}
+	s.postsChanged(ctx)
+	if !wasPublished && post.Status == models.StatusPublished {
+		metrics.PostsPublished.WithLabelValues("manual").Inc()
+	}
 
 	// Reload post with associations
-	return s.postRepo.GetByID(post.ID)
-}
-
-// Helper function to generate slug from title
-func generateSlug(title string) string {
-	slug := strings.ToLower(title)
-	slug = strings.ReplaceAll(slug, " ", "-")
-	// Remove special characters and normalize
-	slug = strings.ReplaceAll(slug, ".", "")
-	slug = strings.ReplaceAll(slug, ",", "")
-	slug = strings.ReplaceAll(slug, "!", "")
-	slug = strings.ReplaceAll(slug, "?", "")
-	slug = strings.ReplaceAll(slug, ":", "")
-	slug = strings.ReplaceAll(slug, ";", "")
-	return slug
+	return s.postRepo.GetByID(ctx, post.ID)
+}
+
+// applyStatus moves a post to the given status and keeps the publication
+// timestamps consistent with it
+func applyStatus(post *models.Post, status models.PostStatus, publishAt *time.Time) error {
+	if publishAt != nil && status != models.StatusScheduled {
+		return ErrPublishAtNotAllowed
+	}
+
+	switch status {
+	case models.StatusScheduled:
+		if publishAt != nil {
+			t := publishAt.UTC()
+			post.PublishAt = &t
+		}
+		if post.PublishAt == nil {
+			return ErrPublishAtRequired
+		}
+	case models.StatusPublished:
+		post.PublishAt = nil
+		if post.PublishedAt == nil {
+			now := time.Now()
+			post.PublishedAt = &now
+		}
+	case models.StatusDraft, models.StatusArchived:
+		post.PublishAt = nil
+	default:
+		return ErrInvalidPostStatus
+	}
+
+	post.Status = status
+	return nil
+}
+
+// uniquePostSlug makes slug unique among posts other than postID
+func (s *postService) uniquePostSlug(ctx context.Context, slug string, postID uuid.UUID) (string, error) {
+	return uniqueSlug(slug, "post", func(slug string) (bool, error) {
+		return s.postRepo.SlugExists(ctx, slug, postID)
+	})
 }
 
 // calculateWordCount counts words in markdown content
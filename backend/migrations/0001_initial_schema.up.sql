@@ -0,0 +1,270 @@
+-- Initial schema, matching what GORM AutoMigrate created before versioned
+-- migrations. Every statement is idempotent, and columns added to existing
+-- tables since the first release are added again with IF NOT EXISTS, so
+-- databases created by any earlier AutoMigrate are brought up to date.
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    username text NOT NULL,
+    email text NOT NULL,
+    password text NOT NULL,
+    name text NOT NULL,
+    bio text,
+    avatar text,
+    role text DEFAULT 'author',
+    is_active boolean DEFAULT true,
+    email_verified_at timestamptz,
+    totp_secret text,
+    totp_enabled boolean DEFAULT false,
+    totp_last_step bigint,
+    failed_login_attempts bigint DEFAULT 0,
+    last_failed_login_at timestamptz,
+    locked_until timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled boolean DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step bigint;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts bigint DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id uuid NOT NULL,
+    family_id uuid NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    replaced_by_id uuid,
+    user_agent text,
+    ip_address text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id uuid NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id uuid NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_verification_tokens_token_hash ON email_verification_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    email text,
+    role text NOT NULL,
+    token_hash text NOT NULL,
+    invited_by_id uuid NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    used_by_id uuid,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations (token_hash);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id uuid NOT NULL,
+    code_hash text NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id uuid NOT NULL,
+    name text NOT NULL,
+    prefix text NOT NULL,
+    token_hash text NOT NULL,
+    scopes text[],
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_token_hash ON api_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    title text NOT NULL,
+    slug text NOT NULL,
+    excerpt text,
+    content text,
+    content_html text,
+    featured_img text,
+    status text DEFAULT 'draft',
+    author_id uuid NOT NULL,
+    view_count bigint DEFAULT 0,
+    reading_time bigint DEFAULT 0,
+    word_count bigint DEFAULT 0,
+    published_at timestamptz,
+    publish_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT uni_posts_slug UNIQUE (slug),
+    CONSTRAINT fk_users_posts FOREIGN KEY (author_id) REFERENCES users (id)
+);
+-- content_html, reading_time and word_count were once added by hand with
+-- migrations/add_markdown_fields.sql
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_html text;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reading_time bigint DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS word_count bigint DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at);
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at);
+
+-- Weighted full-text search column (title > excerpt > content), generated by
+-- PostgreSQL so the application never writes it. The text search
+-- configuration must match SEARCH_LANGUAGE; to change it, write a migration
+-- that drops the column and adds it again with the new configuration.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('portuguese'::regconfig, coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('portuguese'::regconfig, coalesce(excerpt, '')), 'B') ||
+        setweight(to_tsvector('portuguese'::regconfig, coalesce(content, '')), 'C')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    post_id uuid NOT NULL,
+    revision bigint NOT NULL,
+    title text,
+    slug text,
+    excerpt text,
+    content text,
+    featured_img text,
+    status text,
+    category_ids text[],
+    tag_ids text[],
+    editor_id uuid,
+    created_at timestamptz,
+    CONSTRAINT fk_post_revisions_editor FOREIGN KEY (editor_id) REFERENCES users (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_revisions_post_revision ON post_revisions (post_id, revision);
+
+CREATE TABLE IF NOT EXISTS post_slug_histories (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    post_id uuid NOT NULL,
+    slug text NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_post_slug_histories_post_id ON post_slug_histories (post_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_slug_histories_slug ON post_slug_histories (slug);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    name text NOT NULL,
+    slug text NOT NULL,
+    description text,
+    color text DEFAULT '#6B7280',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT uni_categories_name UNIQUE (name),
+    CONSTRAINT uni_categories_slug UNIQUE (slug)
+);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    name text NOT NULL,
+    slug text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT uni_tags_name UNIQUE (name),
+    CONSTRAINT uni_tags_slug UNIQUE (slug)
+);
+CREATE INDEX IF NOT EXISTS idx_tags_deleted_at ON tags (deleted_at);
+
+CREATE TABLE IF NOT EXISTS post_categories (
+    post_id uuid NOT NULL,
+    category_id uuid NOT NULL,
+    PRIMARY KEY (post_id, category_id),
+    CONSTRAINT fk_post_categories_post FOREIGN KEY (post_id) REFERENCES posts (id),
+    CONSTRAINT fk_post_categories_category FOREIGN KEY (category_id) REFERENCES categories (id)
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id uuid NOT NULL,
+    tag_id uuid NOT NULL,
+    PRIMARY KEY (post_id, tag_id),
+    CONSTRAINT fk_post_tags_post FOREIGN KEY (post_id) REFERENCES posts (id),
+    CONSTRAINT fk_post_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    post_id uuid NOT NULL,
+    parent_id uuid,
+    name text NOT NULL,
+    email text NOT NULL,
+    website text,
+    content text NOT NULL,
+    status text DEFAULT 'pending',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_posts_comments FOREIGN KEY (post_id) REFERENCES posts (id),
+    CONSTRAINT fk_comments_replies FOREIGN KEY (parent_id) REFERENCES comments (id)
+);
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at);
+
+CREATE TABLE IF NOT EXISTS newsletters (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    email text NOT NULL,
+    is_active boolean DEFAULT true,
+    token text,
+    confirmed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT uni_newsletters_email UNIQUE (email),
+    CONSTRAINT uni_newsletters_token UNIQUE (token)
+);
+CREATE INDEX IF NOT EXISTS idx_newsletters_deleted_at ON newsletters (deleted_at);
+
+CREATE TABLE IF NOT EXISTS images (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    file_name text NOT NULL,
+    original_name text NOT NULL,
+    mime_type text NOT NULL,
+    size bigint NOT NULL,
+    path text NOT NULL,
+    url text NOT NULL,
+    width bigint,
+    height bigint,
+    uploaded_by uuid NOT NULL,
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_images_uploader FOREIGN KEY (uploaded_by) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_images_deleted_at ON images (deleted_at);
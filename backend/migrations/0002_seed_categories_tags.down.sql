@@ -0,0 +1,30 @@
+-- Removes the default categories and tags that no post uses.
+
+DELETE FROM categories c
+WHERE c.slug IN (
+    'go-basico', 'go-avancado', 'padroes-concorrencia', 'testes-em-go',
+    'performance', 'arquitetura-de-software', 'microsservicos',
+    'domain-driven-design', 'clean-architecture', 'padroes-de-projeto',
+    'sistemas-distribuidos', 'apis-webservices', 'bancos-de-dados',
+    'mensageria', 'devops-infra', 'ci-cd', 'observabilidade', 'seguranca',
+    'carreira-mercado', 'tutoriais', 'estudos-de-caso', 'ferramentas',
+    'boas-praticas', 'projetos-comunidade', 'biblioteca-padrao'
+)
+AND NOT EXISTS (SELECT 1 FROM post_categories pc WHERE pc.category_id = c.id);
+
+DELETE FROM tags t
+WHERE t.slug IN (
+    'golang', 'go-basics', 'goroutines', 'channels', 'interfaces', 'structs',
+    'pointers', 'slices', 'maps', 'functions', 'reflection', 'generics',
+    'modules', 'embedding', 'type-assertion', 'context', 'sync', 'atomic',
+    'testing', 'benchmarks', 'fuzzing', 'testify', 'mocking', 'code-coverage',
+    'profiling', 'gin', 'echo', 'fiber', 'http', 'rest-api', 'grpc', 'graphql',
+    'websockets', 'gorm', 'sqlx', 'postgresql', 'mysql', 'mongodb', 'redis',
+    'migrations', 'clean-architecture', 'hexagonal', 'ddd', 'microservices',
+    'design-patterns', 'docker', 'kubernetes', 'ci-cd', 'github-actions',
+    'monitoring', 'logging', 'aws', 'gcp', 'azure', 'serverless', 'kafka',
+    'performance', 'optimization', 'security', 'authentication', 'jwt',
+    'tutorial', 'beginner', 'intermediate', 'advanced', 'best-practices',
+    'tips', 'opensource', 'community', 'career'
+)
+AND NOT EXISTS (SELECT 1 FROM post_tags pt WHERE pt.tag_id = t.id);
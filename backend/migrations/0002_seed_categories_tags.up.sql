@@ -1,5 +1,5 @@
--- Migration: Insert initial categories and tags
--- Description: Populate database with default categories and tags for the blog
+-- Default categories and tags. Rows that clash with an existing name or slug
+-- are skipped, so the seed is safe to run on a database that already has them.
 
 -- Insert Categories
 INSERT INTO categories (id, name, slug, description, color, created_at, updated_at) VALUES
@@ -37,7 +37,7 @@ INSERT INTO categories (id, name, slug, description, color, created_at, updated_
 (gen_random_uuid(), 'Boas Práticas', 'boas-praticas', 'Melhores práticas e convenções', '#06B6D4', NOW(), NOW()),
 (gen_random_uuid(), 'Projetos da Comunidade', 'projetos-comunidade', 'Projetos open source e da comunidade', '#84CC16', NOW(), NOW()),
 (gen_random_uuid(), 'Biblioteca Padrão', 'biblioteca-padrao', 'Explorando a biblioteca padrão do Go', '#00ADD8', NOW(), NOW())
-ON CONFLICT (slug) DO NOTHING;
+ON CONFLICT DO NOTHING;
 
 -- Insert Tags  
 INSERT INTO tags (id, name, slug, created_at, updated_at) VALUES
@@ -130,4 +130,4 @@ INSERT INTO tags (id, name, slug, created_at, updated_at) VALUES
 (gen_random_uuid(), 'opensource', 'opensource', NOW(), NOW()),
 (gen_random_uuid(), 'community', 'community', NOW(), NOW()),
 (gen_random_uuid(), 'career', 'career', NOW(), NOW())
-ON CONFLICT (slug) DO NOTHING;
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Version counters for optimistic concurrency control. Every update bumps the
+-- version and only applies when the row still has the version it was based on.
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+// Package migrations embeds the versioned SQL migrations of the database.
+//
+// Each migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql; create them with `go run ./cmd/migrate create`.
+// 0001_initial_schema has no down file on purpose: it adopts databases created
+// by AutoMigrate, so rolling it back would drop their data.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
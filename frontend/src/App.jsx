@@ -15,6 +15,9 @@ import PostDetail from './pages/PostDetail';
 import About from './pages/About';
 import Categories from './pages/Categories';
 import Login from './pages/Login';
+import ResetPassword from './pages/ResetPassword';
+import Register from './pages/Register';
+import VerifyEmail from './pages/VerifyEmail';
 import AdminDashboard from './pages/AdminDashboard';
 import PostEditor from './pages/PostEditor';
 import TestPosts from './pages/TestPosts';
@@ -61,6 +64,9 @@ function App() {
 
               {/* Auth Routes (no layout) */}
               <Route path="/login" element={<Login />} />
+              <Route path="/reset-password" element={<ResetPassword />} />
+              <Route path="/register" element={<Register />} />
+              <Route path="/verify-email" element={<VerifyEmail />} />
 
               {/* Admin Routes (protected) */}
               <Route
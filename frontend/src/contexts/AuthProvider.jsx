@@ -36,8 +36,8 @@ export const AuthProvider = ({ children }) => {
     }
   };
 
-  const login = async (email, password) => {
-    const response = await authService.login(email, password);
+  // Marks the user as signed in once a login response carries a session
+  const completeLogin = response => {
     // For mock response, user data is directly in response
     const userData = response.user || response;
     setUser(userData);
@@ -45,24 +45,31 @@ export const AuthProvider = ({ children }) => {
     return response;
   };
 
+  const login = async (email, password) => {
+    const response = await authService.login(email, password);
+    // Two-factor logins continue on the login page with the challenge
+    if (response.two_factor_required || response.two_factor_setup_required) {
+      return response;
+    }
+    return completeLogin(response);
+  };
+
   const logout = () => {
     authService.logout();
     setUser(null);
     setIsAuthenticated(false);
   };
 
-  const register = async (name, email, password) => {
-    const response = await authService.register(name, email, password);
-    setUser(response.user);
-    setIsAuthenticated(true);
-    return response;
-  };
+  // New accounts stay inactive until the email address is verified, so
+  // registering does not log in
+  const register = async data => authService.register(data);
 
   const value = {
     user,
     loading,
     isAuthenticated,
     login,
+    completeLogin,
     logout,
     register,
     checkAuthStatus,
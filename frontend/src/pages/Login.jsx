@@ -8,18 +8,33 @@ import {
   Box,
   Alert,
   CircularProgress,
+  Link,
 } from '@mui/material';
-import { useNavigate, useLocation } from 'react-router-dom';
+import {
+  useNavigate,
+  useLocation,
+  Link as RouterLink,
+} from 'react-router-dom';
 import { Helmet } from 'react-helmet-async';
 import { useAuth } from '../hooks/useAuth';
+import { authService } from '../services';
 
 const Login = () => {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState('');
+  // credentials, verify (code from the authenticator app), enroll (mandatory
+  // two-factor setup) or recovery (codes shown once after enrollment)
+  const [step, setStep] = useState('credentials');
+  const [challengeToken, setChallengeToken] = useState('');
+  const [code, setCode] = useState('');
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false);
+  const [setup, setSetup] = useState(null);
+  const [recoveryCodes, setRecoveryCodes] = useState([]);
+  const [session, setSession] = useState(null);
 
-  const { login } = useAuth();
+  const { login, completeLogin } = useAuth();
   const navigate = useNavigate();
   const location = useLocation();
 
@@ -31,15 +46,118 @@ const Login = () => {
     setLoading(true);
 
     try {
-      await login(email, password);
+      const response = await login(email, password);
+      if (response.two_factor_required) {
+        setChallengeToken(response.challenge_token);
+        setStep('verify');
+      } else if (response.two_factor_setup_required) {
+        setChallengeToken(response.challenge_token);
+        setSetup(await authService.setupTwoFactor(response.challenge_token));
+        setStep('enroll');
+      } else {
+        navigate(from, { replace: true });
+      }
+    } catch (err) {
+      setError(
+        err.response?.data?.error ||
+          err.response?.data?.message ||
+          'Invalid credentials'
+      );
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleVerify = async e => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+
+    try {
+      const response = await authService.loginTwoFactor(
+        challengeToken,
+        useRecoveryCode ? { recoveryCode: code } : { code }
+      );
+      completeLogin(response);
       navigate(from, { replace: true });
     } catch (err) {
-      setError(err.response?.data?.message || 'Invalid credentials');
+      setError(err.response?.data?.error || 'Invalid code');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleEnroll = async e => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+
+    try {
+      const response = await authService.confirmTwoFactor(
+        code,
+        challengeToken
+      );
+      setRecoveryCodes(response.recovery_codes);
+      setSession(response.session);
+      setStep('recovery');
+    } catch (err) {
+      setError(err.response?.data?.error || 'Invalid code');
     } finally {
       setLoading(false);
     }
   };
 
+  const handleContinue = () => {
+    completeLogin(session);
+    navigate(from, { replace: true });
+  };
+
+  const restart = () => {
+    setStep('credentials');
+    setChallengeToken('');
+    setCode('');
+    setUseRecoveryCode(false);
+    setSetup(null);
+    setError('');
+  };
+
+  const codeField = (
+    <TextField
+      fullWidth
+      label={useRecoveryCode ? 'Recovery code' : 'Authentication code'}
+      value={code}
+      onChange={e => setCode(e.target.value)}
+      required
+      autoFocus
+      autoComplete="one-time-code"
+      inputProps={useRecoveryCode ? {} : { inputMode: 'numeric' }}
+      sx={{ mb: 3 }}
+      disabled={loading}
+    />
+  );
+
+  const submitButton = label => (
+    <Button
+      type="submit"
+      fullWidth
+      variant="contained"
+      size="large"
+      disabled={loading}
+      sx={{
+        py: 1.5,
+        fontSize: '1.1rem',
+        textTransform: 'none',
+        mb: 2,
+      }}
+    >
+      {loading ? (
+        <CircularProgress size={24} sx={{ color: 'white' }} />
+      ) : (
+        label
+      )}
+    </Button>
+  );
+
   return (
     <>
       <Helmet>
@@ -63,67 +181,153 @@ const Login = () => {
             </Alert>
           )}
 
-          <Box component="form" onSubmit={handleSubmit}>
-            <TextField
-              fullWidth
-              label="Email"
-              type="email"
-              value={email}
-              onChange={e => setEmail(e.target.value)}
-              required
-              sx={{ mb: 3 }}
-              disabled={loading}
-            />
-
-            <TextField
-              fullWidth
-              label="Password"
-              type="password"
-              value={password}
-              onChange={e => setPassword(e.target.value)}
-              required
-              sx={{ mb: 4 }}
-              disabled={loading}
-            />
-
-            <Button
-              type="submit"
-              fullWidth
-              variant="contained"
-              size="large"
-              disabled={loading}
-              sx={{
-                py: 1.5,
-                fontSize: '1.1rem',
-                textTransform: 'none',
-                mb: 2,
-              }}
-            >
-              {loading ? (
-                <CircularProgress size={24} sx={{ color: 'white' }} />
-              ) : (
-                'Sign In'
-              )}
-            </Button>
-
-            {/* Quick Login Button for Testing */}
-            <Button
-              fullWidth
-              variant="outlined"
-              size="large"
-              onClick={() => {
-                setEmail('admin@blog.com');
-                setPassword('admin123');
-              }}
-              sx={{
-                py: 1.5,
-                fontSize: '1rem',
-                textTransform: 'none',
-              }}
-            >
-              Fill Test Credentials
-            </Button>
-          </Box>
+          {step === 'verify' && (
+            <Box component="form" onSubmit={handleVerify}>
+              <Typography variant="body2" sx={{ mb: 3 }}>
+                {useRecoveryCode
+                  ? 'Enter one of the recovery codes you saved when enabling two-factor authentication.'
+                  : 'Enter the 6-digit code from your authenticator app.'}
+              </Typography>
+              {codeField}
+              {submitButton('Verify')}
+              <Box sx={{ display: 'flex', justifyContent: 'space-between' }}>
+                <Link
+                  component="button"
+                  type="button"
+                  variant="body2"
+                  onClick={() => {
+                    setUseRecoveryCode(!useRecoveryCode);
+                    setCode('');
+                  }}
+                >
+                  {useRecoveryCode
+                    ? 'Use authenticator code'
+                    : 'Use a recovery code'}
+                </Link>
+                <Link
+                  component="button"
+                  type="button"
+                  variant="body2"
+                  onClick={restart}
+                >
+                  Back to sign in
+                </Link>
+              </Box>
+            </Box>
+          )}
+
+          {step === 'enroll' && setup && (
+            <Box component="form" onSubmit={handleEnroll}>
+              <Alert severity="info" sx={{ mb: 3 }}>
+                Your account requires two-factor authentication. Add this
+                account to your authenticator app, then enter the code it
+                shows.
+              </Alert>
+              <TextField
+                fullWidth
+                label="Secret key"
+                value={setup.secret}
+                InputProps={{ readOnly: true }}
+                sx={{ mb: 2 }}
+              />
+              <Typography
+                variant="body2"
+                sx={{ mb: 3, wordBreak: 'break-all' }}
+              >
+                <Link href={setup.otpauth_url}>{setup.otpauth_url}</Link>
+              </Typography>
+              {codeField}
+              {submitButton('Enable two-factor authentication')}
+            </Box>
+          )}
+
+          {step === 'recovery' && (
+            <Box>
+              <Alert severity="warning" sx={{ mb: 3 }}>
+                Save these recovery codes somewhere safe. Each one can be used
+                once to sign in without your authenticator app, and they will
+                not be shown again.
+              </Alert>
+              <Box
+                component="pre"
+                sx={{
+                  p: 2,
+                  mb: 3,
+                  bgcolor: 'grey.100',
+                  borderRadius: 1,
+                  fontFamily: 'monospace',
+                  textAlign: 'center',
+                }}
+              >
+                {recoveryCodes.join('\n')}
+              </Box>
+              <Button
+                fullWidth
+                variant="contained"
+                size="large"
+                onClick={handleContinue}
+                sx={{ py: 1.5, fontSize: '1.1rem', textTransform: 'none' }}
+              >
+                Continue
+              </Button>
+            </Box>
+          )}
+
+          {step === 'credentials' && (
+            <Box component="form" onSubmit={handleSubmit}>
+              <TextField
+                fullWidth
+                label="Email"
+                type="email"
+                value={email}
+                onChange={e => setEmail(e.target.value)}
+                required
+                sx={{ mb: 3 }}
+                disabled={loading}
+              />
+
+              <TextField
+                fullWidth
+                label="Password"
+                type="password"
+                value={password}
+                onChange={e => setPassword(e.target.value)}
+                required
+                sx={{ mb: 4 }}
+                disabled={loading}
+              />
+
+              {submitButton('Sign In')}
+
+              <Box sx={{ textAlign: 'center', mb: 2 }}>
+                <Link
+                  component={RouterLink}
+                  to="/reset-password"
+                  variant="body2"
+                >
+                  Forgot your password?
+                </Link>
+              </Box>
+
+              {/* Quick Login Button for Testing */}
+              <Button
+                fullWidth
+                variant="outlined"
+                size="large"
+                onClick={() => {
+                  setEmail('admin@blog.com');
+                  setPassword('admin123');
+                }}
+                sx={{
+                  py: 1.5,
+                  fontSize: '1rem',
+                  textTransform: 'none',
+                }}
+              >
+                Fill Test Credentials
+              </Button>
+            </Box>
+          )}
         </Paper>
       </Container>
     </>
@@ -36,6 +36,11 @@ const PostDetail = () => {
       try {
         setLoading(true);
         const response = await postsService.getPostBySlug(slug);
+        // Renamed posts answer with the slug they moved to
+        if (response.data?.redirect) {
+          navigate(`/blog/${response.data.slug}`, { replace: true });
+          return;
+        }
         setPost(response.data);
       } catch (err) {
         console.error('Error fetching post:', err);
@@ -48,7 +53,7 @@ const PostDetail = () => {
     if (slug) {
       fetchPost();
     }
-  }, [slug]);
+  }, [slug, navigate]);
 
   if (loading) {
     return (
@@ -23,6 +23,10 @@ import PostSettings from '../components/editor/PostSettings';
 import PostImageUpload from '../components/editor/PostImageUpload';
 import PostActionBar from '../components/editor/PostActionBar';
 
+// Shown when someone else saved the post since it was loaded
+const conflictMessage =
+  'This post was changed by someone else. Reload it to get the latest version before saving.';
+
 const PostEditor = () => {
   const [post, setPost] = useState({
     id: '',
@@ -124,10 +128,15 @@ const PostEditor = () => {
 
     try {
       const postData = { ...post, status: 'draft' };
-      await postsService.updatePost(id, postData);
+      const response = await postsService.updatePost(id, postData);
+      // Later saves are based on the version just written
+      setPost(prev => ({ ...prev, version: response.version }));
       setLastSaved(new Date());
     } catch (error) {
       console.error('Auto-save failed:', error);
+      if (error.response?.status === 409) {
+        setError(conflictMessage);
+      }
     }
   };
 
@@ -241,8 +250,8 @@ const PostEditor = () => {
           `Post ${status === 'published' ? 'published' : 'saved'} successfully!`
         );
 
-        // Update local state to reflect the new status
-        setPost(prev => ({ ...prev, status }));
+        // Update local state to reflect the new status and version
+        setPost(prev => ({ ...prev, status, version: response.version }));
       } else {
         const response = await postsService.createPost(postData);
         console.log('Create response:', response);
@@ -254,7 +263,11 @@ const PostEditor = () => {
     } catch (err) {
       console.error('Submit error:', err);
       console.error('Error response:', err.response?.data);
-      setError(err.response?.data?.message || 'Failed to save post');
+      setError(
+        err.response?.status === 409
+          ? conflictMessage
+          : err.response?.data?.message || 'Failed to save post'
+      );
     } finally {
       setLoading(false);
     }
@@ -0,0 +1,180 @@
+import { useState } from 'react';
+import {
+  Container,
+  Paper,
+  TextField,
+  Button,
+  Typography,
+  Box,
+  Alert,
+  CircularProgress,
+  Link,
+} from '@mui/material';
+import { useSearchParams, Link as RouterLink } from 'react-router-dom';
+import { Helmet } from 'react-helmet-async';
+import { useAuth } from '../hooks/useAuth';
+
+// Invitation links point here with ?invitation=<token>. Depending on the
+// server's registration mode, registering without one may be refused.
+const Register = () => {
+  const [searchParams] = useSearchParams();
+  const invitationToken = searchParams.get('invitation') || '';
+
+  const [form, setForm] = useState({
+    name: '',
+    username: '',
+    email: '',
+    password: '',
+    confirmPassword: '',
+  });
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+  const [registered, setRegistered] = useState(false);
+
+  const { register } = useAuth();
+
+  const handleChange = field => e =>
+    setForm({ ...form, [field]: e.target.value });
+
+  const handleSubmit = async e => {
+    e.preventDefault();
+    setError('');
+
+    if (form.password !== form.confirmPassword) {
+      setError('Passwords do not match');
+      return;
+    }
+
+    setLoading(true);
+    try {
+      await register({
+        name: form.name,
+        username: form.username,
+        email: form.email,
+        password: form.password,
+        invitationToken,
+      });
+      setRegistered(true);
+    } catch (err) {
+      setError(err.response?.data?.error || 'Could not create the account');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <>
+      <Helmet>
+        <title>Create Account - MyBlog</title>
+      </Helmet>
+
+      <Container maxWidth="sm" sx={{ py: 8 }}>
+        <Paper elevation={3} sx={{ p: 4 }}>
+          <Box sx={{ textAlign: 'center', mb: 4 }}>
+            <Typography variant="h4" sx={{ fontWeight: 600, mb: 1 }}>
+              Create Account
+            </Typography>
+            <Typography variant="body2" sx={{ color: 'text.secondary' }}>
+              {invitationToken
+                ? 'You have been invited to write for the blog'
+                : 'Sign up to write for the blog'}
+            </Typography>
+          </Box>
+
+          {error && (
+            <Alert severity="error" sx={{ mb: 3 }}>
+              {error}
+            </Alert>
+          )}
+
+          {registered ? (
+            <Alert severity="success" sx={{ mb: 3 }}>
+              Account created. We sent a link to {form.email} to verify your
+              email address; your account is activated once you open it.
+            </Alert>
+          ) : (
+            <Box component="form" onSubmit={handleSubmit}>
+              <TextField
+                fullWidth
+                label="Name"
+                value={form.name}
+                onChange={handleChange('name')}
+                required
+                sx={{ mb: 3 }}
+                disabled={loading}
+              />
+              <TextField
+                fullWidth
+                label="Username"
+                value={form.username}
+                onChange={handleChange('username')}
+                required
+                sx={{ mb: 3 }}
+                disabled={loading}
+              />
+              <TextField
+                fullWidth
+                label="Email"
+                type="email"
+                value={form.email}
+                onChange={handleChange('email')}
+                required
+                sx={{ mb: 3 }}
+                disabled={loading}
+              />
+              <TextField
+                fullWidth
+                label="Password"
+                type="password"
+                value={form.password}
+                onChange={handleChange('password')}
+                required
+                inputProps={{ minLength: 6 }}
+                sx={{ mb: 3 }}
+                disabled={loading}
+              />
+              <TextField
+                fullWidth
+                label="Confirm password"
+                type="password"
+                value={form.confirmPassword}
+                onChange={handleChange('confirmPassword')}
+                required
+                sx={{ mb: 4 }}
+                disabled={loading}
+              />
+
+              <Button
+                type="submit"
+                fullWidth
+                variant="contained"
+                size="large"
+                disabled={loading}
+                sx={{
+                  py: 1.5,
+                  fontSize: '1.1rem',
+                  textTransform: 'none',
+                  mb: 2,
+                }}
+              >
+                {loading ? (
+                  <CircularProgress size={24} sx={{ color: 'white' }} />
+                ) : (
+                  'Create Account'
+                )}
+              </Button>
+            </Box>
+          )}
+
+          <Box sx={{ textAlign: 'center' }}>
+            <Link component={RouterLink} to="/login" variant="body2">
+              Back to login
+            </Link>
+          </Box>
+        </Paper>
+      </Container>
+    </>
+  );
+};
+
+export default Register;
@@ -0,0 +1,171 @@
+import { useState } from 'react';
+import {
+  Container,
+  Paper,
+  TextField,
+  Button,
+  Typography,
+  Box,
+  Alert,
+  CircularProgress,
+  Link,
+} from '@mui/material';
+import { useSearchParams, Link as RouterLink } from 'react-router-dom';
+import { Helmet } from 'react-helmet-async';
+import { authService } from '../services';
+
+// Without a token the page asks for the account email; with the token from the
+// reset email it lets the user choose a new password
+const ResetPassword = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+  const [message, setMessage] = useState('');
+
+  const handleRequest = async e => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+
+    try {
+      const response = await authService.forgotPassword(email);
+      setMessage(response.message);
+    } catch (err) {
+      setError(err.response?.data?.error || 'Could not send the reset email');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleReset = async e => {
+    e.preventDefault();
+    setError('');
+
+    if (password !== confirmPassword) {
+      setError('Passwords do not match');
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const response = await authService.resetPassword(token, password);
+      setMessage(response.message);
+    } catch (err) {
+      setError(err.response?.data?.error || 'Could not reset the password');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <>
+      <Helmet>
+        <title>Reset Password - MyBlog Admin</title>
+      </Helmet>
+
+      <Container maxWidth="sm" sx={{ py: 8 }}>
+        <Paper elevation={3} sx={{ p: 4 }}>
+          <Box sx={{ textAlign: 'center', mb: 4 }}>
+            <Typography variant="h4" sx={{ fontWeight: 600, mb: 1 }}>
+              Reset Password
+            </Typography>
+            <Typography variant="body2" sx={{ color: 'text.secondary' }}>
+              {token
+                ? 'Choose a new password for your account'
+                : 'We will email you a link to reset your password'}
+            </Typography>
+          </Box>
+
+          {error && (
+            <Alert severity="error" sx={{ mb: 3 }}>
+              {error}
+            </Alert>
+          )}
+
+          {message ? (
+            <Alert severity="success" sx={{ mb: 3 }}>
+              {message}
+            </Alert>
+          ) : (
+            <Box
+              component="form"
+              onSubmit={token ? handleReset : handleRequest}
+            >
+              {token ? (
+                <>
+                  <TextField
+                    fullWidth
+                    label="New password"
+                    type="password"
+                    value={password}
+                    onChange={e => setPassword(e.target.value)}
+                    required
+                    inputProps={{ minLength: 6 }}
+                    sx={{ mb: 3 }}
+                    disabled={loading}
+                  />
+                  <TextField
+                    fullWidth
+                    label="Confirm new password"
+                    type="password"
+                    value={confirmPassword}
+                    onChange={e => setConfirmPassword(e.target.value)}
+                    required
+                    sx={{ mb: 4 }}
+                    disabled={loading}
+                  />
+                </>
+              ) : (
+                <TextField
+                  fullWidth
+                  label="Email"
+                  type="email"
+                  value={email}
+                  onChange={e => setEmail(e.target.value)}
+                  required
+                  sx={{ mb: 4 }}
+                  disabled={loading}
+                />
+              )}
+
+              <Button
+                type="submit"
+                fullWidth
+                variant="contained"
+                size="large"
+                disabled={loading}
+                sx={{
+                  py: 1.5,
+                  fontSize: '1.1rem',
+                  textTransform: 'none',
+                  mb: 2,
+                }}
+              >
+                {loading ? (
+                  <CircularProgress size={24} sx={{ color: 'white' }} />
+                ) : token ? (
+                  'Set New Password'
+                ) : (
+                  'Send Reset Link'
+                )}
+              </Button>
+            </Box>
+          )}
+
+          <Box sx={{ textAlign: 'center' }}>
+            <Link component={RouterLink} to="/login" variant="body2">
+              Back to login
+            </Link>
+          </Box>
+        </Paper>
+      </Container>
+    </>
+  );
+};
+
+export default ResetPassword;
@@ -0,0 +1,142 @@
+import { useEffect, useRef, useState } from 'react';
+import {
+  Container,
+  Paper,
+  TextField,
+  Button,
+  Typography,
+  Box,
+  Alert,
+  CircularProgress,
+  Link,
+} from '@mui/material';
+import { useSearchParams, Link as RouterLink } from 'react-router-dom';
+import { Helmet } from 'react-helmet-async';
+import { authService } from '../services';
+
+// Opened from the verification email. If the link is invalid or expired the
+// user can ask for a new one.
+const VerifyEmail = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+
+  const [verifying, setVerifying] = useState(!!token);
+  const [email, setEmail] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState(
+    token ? '' : 'The verification link is missing its token'
+  );
+  const [message, setMessage] = useState('');
+  // Tokens are deleted once used, so a second request would always fail
+  const requested = useRef(false);
+
+  useEffect(() => {
+    if (!token || requested.current) return;
+    requested.current = true;
+
+    authService
+      .verifyEmail(token)
+      .then(response => setMessage(response.message))
+      .catch(err =>
+        setError(
+          err.response?.data?.error || 'Could not verify the email address'
+        )
+      )
+      .finally(() => setVerifying(false));
+  }, [token]);
+
+  const handleResend = async e => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+
+    try {
+      const response = await authService.resendVerification(email);
+      setMessage(response.message);
+    } catch (err) {
+      setError(
+        err.response?.data?.error || 'Could not send the verification email'
+      );
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <>
+      <Helmet>
+        <title>Verify Email - MyBlog</title>
+      </Helmet>
+
+      <Container maxWidth="sm" sx={{ py: 8 }}>
+        <Paper elevation={3} sx={{ p: 4 }}>
+          <Box sx={{ textAlign: 'center', mb: 4 }}>
+            <Typography variant="h4" sx={{ fontWeight: 600, mb: 1 }}>
+              Verify Email
+            </Typography>
+          </Box>
+
+          {verifying && (
+            <Box sx={{ textAlign: 'center', mb: 3 }}>
+              <CircularProgress />
+            </Box>
+          )}
+
+          {message && (
+            <Alert severity="success" sx={{ mb: 3 }}>
+              {message}
+            </Alert>
+          )}
+
+          {error && !message && (
+            <>
+              <Alert severity="error" sx={{ mb: 3 }}>
+                {error}
+              </Alert>
+
+              <Box component="form" onSubmit={handleResend}>
+                <TextField
+                  fullWidth
+                  label="Email"
+                  type="email"
+                  value={email}
+                  onChange={e => setEmail(e.target.value)}
+                  required
+                  sx={{ mb: 3 }}
+                  disabled={loading}
+                />
+                <Button
+                  type="submit"
+                  fullWidth
+                  variant="contained"
+                  size="large"
+                  disabled={loading}
+                  sx={{
+                    py: 1.5,
+                    fontSize: '1.1rem',
+                    textTransform: 'none',
+                    mb: 2,
+                  }}
+                >
+                  {loading ? (
+                    <CircularProgress size={24} sx={{ color: 'white' }} />
+                  ) : (
+                    'Send a New Link'
+                  )}
+                </Button>
+              </Box>
+            </>
+          )}
+
+          <Box sx={{ textAlign: 'center' }}>
+            <Link component={RouterLink} to="/login" variant="body2">
+              Go to login
+            </Link>
+          </Box>
+        </Paper>
+      </Container>
+    </>
+  );
+};
+
+export default VerifyEmail;
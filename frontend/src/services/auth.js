@@ -1,5 +1,19 @@
 import api from './api';
 
+const storeSession = data => {
+  if (data.token) {
+    localStorage.setItem('token', data.token);
+    localStorage.setItem('refresh_token', data.refresh_token);
+  }
+};
+
+// Enrollment during login is authorized by the login challenge instead of a
+// session token
+const challengeHeaders = challengeToken =>
+  challengeToken
+    ? { headers: { Authorization: `Bearer ${challengeToken}` } }
+    : {};
+
 export const authService = {
   // Login user
   login: async (email, password) => {
@@ -9,10 +23,9 @@ export const authService = {
         password,
       });
 
-      // Store token in localStorage
-      if (response.data.token) {
-        localStorage.setItem('token', response.data.token);
-      }
+      // Store tokens in localStorage. Accounts with two-factor authentication
+      // get a challenge instead and finish with loginTwoFactor.
+      storeSession(response.data);
       return response.data;
     } catch (error) {
       // Mock login for testing when backend is not available
@@ -42,37 +55,126 @@ export const authService = {
     }
   },
 
-  // Register user
-  register: async (name, email, password) => {
+  // Finish a login with an authenticator or recovery code
+  loginTwoFactor: async (challengeToken, { code, recoveryCode }) => {
+    const response = await api.post('/auth/login/2fa', {
+      challenge_token: challengeToken,
+      code,
+      recovery_code: recoveryCode,
+    });
+    storeSession(response.data);
+    return response.data;
+  },
+
+  // Start two-factor enrollment, returning the secret for the authenticator app
+  setupTwoFactor: async challengeToken => {
+    const response = await api.post(
+      '/auth/2fa/setup',
+      {},
+      challengeHeaders(challengeToken)
+    );
+    return response.data;
+  },
+
+  // Enable two-factor authentication with a first code. When enrolling during
+  // login the response also carries the new session.
+  confirmTwoFactor: async (code, challengeToken) => {
+    const response = await api.post(
+      '/auth/2fa/confirm',
+      { code },
+      challengeHeaders(challengeToken)
+    );
+    if (response.data.session) {
+      storeSession(response.data.session);
+    }
+    return response.data;
+  },
+
+  disableTwoFactor: async (password, code) => {
+    const response = await api.post('/auth/2fa/disable', { password, code });
+    return response.data;
+  },
+
+  regenerateRecoveryCodes: async code => {
+    const response = await api.post('/auth/2fa/recovery-codes', { code });
+    return response.data;
+  },
+
+  // Register user. The account is activated from the verification email.
+  register: async ({ username, name, email, password, invitationToken }) => {
     const response = await api.post('/auth/register', {
+      username,
       name,
       email,
       password,
+      invitation_token: invitationToken,
     });
+    return response.data;
+  },
 
-    // Store token in localStorage if provided
-    if (response.data.token) {
-      localStorage.setItem('token', response.data.token);
-    }
+  // Activate an account with the token from the verification email
+  verifyEmail: async token => {
+    const response = await api.post('/auth/verify-email', { token });
+    return response.data;
+  },
 
+  resendVerification: async email => {
+    const response = await api.post('/auth/resend-verification', { email });
     return response.data;
   },
 
   // Refresh token
   refreshToken: async () => {
-    const response = await api.post('/auth/refresh');
+    const response = await api.post('/auth/refresh', {
+      refresh_token: localStorage.getItem('refresh_token'),
+    });
 
-    // Update token in localStorage
+    // Update tokens in localStorage
     if (response.data.token) {
       localStorage.setItem('token', response.data.token);
+      localStorage.setItem('refresh_token', response.data.refresh_token);
     }
 
     return response.data;
   },
 
-  // Logout user
-  logout: () => {
+  // Logout user, ending the session on the server as well
+  logout: async () => {
+    const refreshToken = localStorage.getItem('refresh_token');
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+    if (refreshToken) {
+      try {
+        await api.post('/auth/logout', { refresh_token: refreshToken });
+      } catch {
+        // The session is gone locally either way
+      }
+    }
+  },
+
+  // Logout from every device
+  logoutAll: async () => {
+    try {
+      await api.post('/auth/logout-all');
+    } finally {
+      localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
+    }
+  },
+
+  // Request a password reset email
+  forgotPassword: async email => {
+    const response = await api.post('/auth/forgot-password', { email });
+    return response.data;
+  },
+
+  // Choose a new password with the token from the reset email
+  resetPassword: async (token, password) => {
+    const response = await api.post('/auth/reset-password', {
+      token,
+      password,
+    });
+    return response.data;
   },
 
   // Check if user is authenticated